@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ageUnits are the units of ages longer than Go durations have.
+var ageUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour,
+}
+
+// parseAge parses an age like 180d, 2w or 36h: a whole number of days, weeks or years,
+// or anything time.ParseDuration accepts.
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range ageUnits {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.ParseUint(n, 10, 32)
+			if err != nil {
+				return 0, fmt.Errorf("invalid age '%v'", s)
+			}
+
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age '%v'", s)
+	}
+
+	return d, nil
+}
+
+// isReportedAge tells whether a file may be reported given -older-than and -newer-than,
+// directories are reported whatever their modification times.
+func (v *visualiser) isReportedAge(e *entry) bool {
+	if e.typ != entryFile {
+		return true
+	}
+
+	return (v.modifiedBefore.IsZero() || e.mtime.Before(v.modifiedBefore)) &&
+		(v.modifiedAfter.IsZero() || e.mtime.After(v.modifiedAfter))
+}
+
+// isReportedAccess tells whether a file may be reported given -not-accessed-since, files
+// whose access times are unknown are not.
+func (v *visualiser) isReportedAccess(e *entry) bool {
+	if e.typ != entryFile || v.accessedBefore.IsZero() {
+		return true
+	}
+
+	return !e.atime.IsZero() && e.atime.Before(v.accessedBefore)
+}
+
+// noteAccessTimes warns once per file system the scan enters if its access times cannot
+// be relied on for -not-accessed-since.
+func (v *visualiser) noteAccessTimes(mountPoint string, fs *fileSystem) {
+	if v.accessedBefore.IsZero() || fs == nil || fs.accessTimes == "" {
+		return
+	}
+
+	v.accessNotedMu.Lock()
+	defer v.accessNotedMu.Unlock()
+
+	if v.accessNoted[fs] {
+		return
+	}
+	v.accessNoted[fs] = true
+
+	switch fs.accessTimes {
+	case "noatime":
+		log.Printf("warning: %v is mounted with noatime, access times of files on it are not updated when they are read", mountPoint)
+	case "relatime":
+		log.Printf("info: %v is mounted with relatime, access times of files on it are only updated once a day", mountPoint)
+	}
+}
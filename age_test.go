@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{s: "180d", want: 180 * 24 * time.Hour},
+		{s: "2w", want: 14 * 24 * time.Hour},
+		{s: "1y", want: 365 * 24 * time.Hour},
+		{s: "36h", want: 36 * time.Hour},
+		{s: "90m", want: 90 * time.Minute},
+		{s: "1h30m", want: 90 * time.Minute},
+		{s: "0d", want: 0},
+		{s: "", wantErr: true},
+		{s: "d", wantErr: true},
+		{s: "1.5d", wantErr: true},
+		{s: "-1d", wantErr: true},
+		{s: "-1h", wantErr: true},
+		{s: "10", wantErr: true},
+		{s: "week", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAge(tt.s)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseAge(%q) = %v, want an error", tt.s, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseAge(%q) failed: %v", tt.s, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseAge(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime returns the time the file was last read, NTFS updates it lazily and not at
+// all if last access updates are disabled in the registry.
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, attrs.LastAccessTime.Nanoseconds()), true
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	benchStrategyReadDir = "readdir"
+	benchStrategyRaw     = "raw"
+
+	// benchStatSize approximates how much metadata the kernel hands out per stat-ed
+	// entry, it is the size of struct stat on 64-bit Linux.
+	benchStatSize = 144
+)
+
+// benchProgress counts what the scan went through.
+type benchProgress struct {
+	noProgress
+
+	dirs, files, names int64
+}
+
+func (p *benchProgress) enterDir(dir *entry) {
+	p.dirs++
+	p.names += int64(len(dir.name))
+}
+
+func (p *benchProgress) addFile(e *entry) {
+	p.files++
+	p.names += int64(len(e.name))
+}
+
+// benchMain implements the bench subcommand, which scans the directories with different
+// numbers of jobs and ways of reading directories and reports how fast each one is.
+func benchMain(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	scanFlags := addScanFlags(fs)
+	jobsList := fs.String("jobs-list", defaultBenchJobs(), "comma-separated numbers of jobs to try")
+	strategies := fs.String("strategies", benchStrategyReadDir+","+benchStrategyRaw, "comma-separated ways of reading directories to try: "+benchStrategyReadDir+", "+benchStrategyRaw)
+	runs := fs.Int("runs", 1, "number of scans for each combination, the fastest one is reported")
+	noWarmup := fs.Bool("no-warmup", false, "do not scan once before measuring, so that the first combination runs with cold caches")
+	fs.Parse(args)
+
+	var jobs []int
+	for _, s := range strings.Split(*jobsList, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 1 {
+			log.Fatalf("invalid number of jobs '%v'", s)
+		}
+		jobs = append(jobs, n)
+	}
+
+	var raws []bool
+	for _, s := range strings.Split(*strategies, ",") {
+		switch strings.TrimSpace(s) {
+		case benchStrategyReadDir:
+			raws = append(raws, false)
+		case benchStrategyRaw:
+			raws = append(raws, true)
+		default:
+			log.Fatalf("unknown strategy '%v'", s)
+		}
+	}
+
+	if !*noWarmup {
+		if _, err := benchScan(scanFlags, jobs[len(jobs)-1], false); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "strategy\tjobs\tentries\ttime\tentries/s\tmetadata MB/s\t")
+
+	for _, raw := range raws {
+		strategy := benchStrategyReadDir
+		if raw {
+			strategy = benchStrategyRaw
+		}
+
+		for _, n := range jobs {
+			var best *benchResult
+			for i := 0; i < *runs; i++ {
+				res, err := benchScan(scanFlags, n, raw)
+				if err != nil {
+					log.Fatalf("%v", err)
+				}
+				if best == nil || res.elapsed < best.elapsed {
+					best = res
+				}
+			}
+
+			secs := best.elapsed.Seconds()
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%.0f\t%.2f\t\n",
+				strategy, n, best.entries, best.elapsed.Round(time.Millisecond),
+				float64(best.entries)/secs, float64(best.metadata)/secs/1e6,
+			)
+		}
+	}
+
+	w.Flush()
+}
+
+func defaultBenchJobs() string {
+	var jobs []string
+	for n := 1; n < 2*runtime.GOMAXPROCS(0); n *= 2 {
+		jobs = append(jobs, strconv.Itoa(n))
+	}
+	jobs = append(jobs, strconv.Itoa(2*runtime.GOMAXPROCS(0)))
+
+	return strings.Join(jobs, ",")
+}
+
+type benchResult struct {
+	elapsed  time.Duration
+	entries  int64
+	metadata int64
+}
+
+func benchScan(scanFlags *scanFlags, jobs int, raw bool) (*benchResult, error) {
+	p := &benchProgress{}
+
+	opts := scanFlags.options(p)
+	opts.jobs = jobs
+	opts.rawReadDir = raw
+	// nothing is reported, only the scan itself is measured
+	opts.sizeThreshold = "1EB"
+
+	visualiser, err := newVisualiser(opts, newTextReporter(io.Discard, reportOptions{}))
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	if err := visualiser.visualise(context.Background(), scanFlags.dirs()...); err != nil {
+		return nil, err
+	}
+
+	entries := p.dirs + p.files
+
+	return &benchResult{
+		elapsed:  time.Since(started),
+		entries:  entries,
+		metadata: p.names + entries*benchStatSize,
+	}, nil
+}
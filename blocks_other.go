@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd
+
+package main
+
+import "os"
+
+func allocatedSize(info os.FileInfo) (int64, bool) {
+	return 0, false
+}
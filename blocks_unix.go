@@ -0,0 +1,23 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// allocatedSize returns the space the file takes on disk.
+func allocatedSize(info os.FileInfo) (int64, bool) {
+	if c, ok := info.(cachedEntry); ok {
+		return c.Allocated, c.HasAllocated
+	}
+
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+
+	// st_blocks is in 512-byte units whatever the block size of the filesystem is
+	return int64(st.Blocks) * 512, true
+}
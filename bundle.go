@@ -0,0 +1,42 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// bundleExts are the extensions of macOS bundles, directories presented to the user as
+// single files.
+var bundleExts = map[string]bool{
+	".app":           true,
+	".bundle":        true,
+	".framework":     true,
+	".plugin":        true,
+	".kext":          true,
+	".dsym":          true,
+	".xcarchive":     true,
+	".photoslibrary": true,
+	".musiclibrary":  true,
+	".logicx":        true,
+	".sparsebundle":  true,
+}
+
+func isBundle(e *entry) bool {
+	return e.typ == entryDir && bundleExts[strings.ToLower(filepath.Ext(e.name))]
+}
+
+// inBundle tells whether the entry lies within a bundle, the bundles themselves are
+// reported as leaves with -bundles. A bundle given as a root is looked into.
+func (v *visualiser) inBundle(e *entry) bool {
+	if !v.bundles {
+		return false
+	}
+
+	for p := e.parent; p != nil && p.parent != nil; p = p.parent {
+		if isBundle(p) {
+			return true
+		}
+	}
+
+	return false
+}
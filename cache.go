@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const cacheFileName = "dirs.cache"
+
+// cachedEntry is an entry of a cached directory listing, it pretends to be a directory
+// entry that was just read so that cached listings are scanned the usual way.
+type cachedEntry struct {
+	Base     string
+	Subdir   bool
+	Symlink  bool
+	Length   int64
+	Modified time.Time
+
+	// Special is the type of a socket, a named pipe or a device, zero for the rest.
+	Special fs.FileMode
+
+	// Perm holds the permission bits of a file together with setuid, setgid and sticky.
+	Perm fs.FileMode
+
+	// Allocated is the space the file takes on disk, it is known if HasAllocated is set.
+	Allocated    int64
+	HasAllocated bool
+
+	// UID and GID own the file, they are known if HasOwner is set.
+	UID      uint32
+	GID      uint32
+	HasOwner bool
+
+	// Dev and Ino identify a file with several hard links, they are zero otherwise.
+	Dev uint64
+	Ino uint64
+}
+
+// cachedDir is the listing of a directory as of its modification time. Size is the
+// size of the whole directory, it is only a hint as nested directories may change
+// without changing the mtime of this one.
+type cachedDir struct {
+	Mtime   time.Time
+	Entries []cachedEntry
+	Size    int64
+}
+
+// dirCache remembers listings of directories between runs. A directory whose mtime did
+// not change has the same entries, so neither reading it nor stat-ing its files is
+// needed. Files modified in place do not change the mtime of their directory, so their
+// new sizes are not noticed until something is added to or removed from it.
+type dirCache struct {
+	path string
+
+	// merge keeps the listings of directories outside of the scanned root, so that
+	// scanning a part of the tree does not forget the rest of it.
+	merge bool
+	roots []string
+
+	// cwd is the working directory relative paths are resolved against for the keys.
+	cwd string
+
+	mu      sync.Mutex
+	old     map[string]*cachedDir
+	seen    map[string]*cachedDir
+	dropped bool
+}
+
+func defaultCacheFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, progName, cacheFileName), nil
+}
+
+func newDirCache(path string) *dirCache {
+	return &dirCache{
+		path: path,
+		old:  map[string]*cachedDir{},
+		seen: map[string]*cachedDir{},
+	}
+}
+
+// loadDirCache reads the cache file, a missing file gives an empty cache.
+func loadDirCache(path string) (*dirCache, error) {
+	c := newDirCache(path)
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open cache '%v': %v", path, err)
+	}
+	defer f.Close()
+
+	if err := gob.NewDecoder(f).Decode(&c.old); err != nil {
+		return nil, fmt.Errorf("could not read cache '%v': %v", path, err)
+	}
+
+	return c, nil
+}
+
+// lookup returns the cached listing of the directory if it was not modified since.
+func (c *dirCache) lookup(path string, mtime time.Time) []cachedEntry {
+	if mtime.IsZero() {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := c.key(path)
+
+	d := c.old[key]
+	if d == nil || !d.Mtime.Equal(mtime) {
+		return nil
+	}
+
+	c.seen[key] = d
+
+	return d.Entries
+}
+
+// sizeHint returns the size the directory had during the previous scan.
+func (c *dirCache) sizeHint(path string) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d := c.old[c.key(path)]
+	if d == nil {
+		return 0, false
+	}
+
+	return d.Size, true
+}
+
+// setSize remembers the size of the directory once it is scanned.
+func (c *dirCache) setSize(path string, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if d := c.seen[c.key(path)]; d != nil {
+		d.Size = size
+	}
+}
+
+// store remembers the listing just read from the directory. Listings with entries that
+// could not be stat-ed are not remembered so that the errors are reported again.
+func (c *dirCache) store(path string, mtime time.Time, entries []listedEntry) {
+	if mtime.IsZero() {
+		return
+	}
+
+	d := &cachedDir{Mtime: mtime}
+	for _, le := range entries {
+		if le.infoErr != nil {
+			return
+		}
+
+		switch {
+		case le.Type().IsRegular():
+			ce := cachedEntry{
+				Base:     le.Name(),
+				Length:   le.info.Size(),
+				Modified: le.info.ModTime(),
+				Perm:     le.info.Mode() &^ fs.ModeType,
+			}
+			if id, ok := linkedFileID(le.info); ok {
+				ce.Dev, ce.Ino = id.dev, id.ino
+			}
+			if allocated, ok := allocatedSize(le.info); ok {
+				ce.Allocated, ce.HasAllocated = allocated, true
+			}
+			if uid, gid, ok := fileOwner(le.info); ok {
+				ce.UID, ce.GID, ce.HasOwner = uid, gid, true
+			}
+
+			d.Entries = append(d.Entries, ce)
+		case le.Type().IsDir():
+			d.Entries = append(d.Entries, cachedEntry{Base: le.Name(), Subdir: true})
+		case isLink(le.Type()):
+			d.Entries = append(d.Entries, cachedEntry{Base: le.Name(), Symlink: true})
+		default:
+			d.Entries = append(d.Entries, cachedEntry{Base: le.Name(), Special: le.Type()})
+		}
+	}
+
+	c.mu.Lock()
+	if !c.dropped {
+		c.seen[c.key(path)] = d
+	}
+	c.mu.Unlock()
+}
+
+// drop forgets everything, saving the cache does nothing from then on.
+func (c *dirCache) drop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.old = map[string]*cachedDir{}
+	c.seen = map[string]*cachedDir{}
+	c.dropped = true
+}
+
+// save replaces the cache file with the listings seen during the scan, it may be called
+// while the scan is still going.
+func (c *dirCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dropped {
+		return nil
+	}
+
+	if c.merge {
+		for key, d := range c.old {
+			if _, ok := c.seen[key]; !ok && !c.isScanned(key) {
+				c.seen[key] = d
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), cacheFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(c.seen); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
+
+func (c *dirCache) key(path string) string {
+	return resolvePath(c.cwd, path)
+}
+
+// isScanned reports whether the directory lies within one of the scanned roots.
+func (c *dirCache) isScanned(key string) bool {
+	for _, root := range c.roots {
+		if isWithin(key, root) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isWithin reports whether the path is the dir itself or lies inside of it.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (e cachedEntry) Type() fs.FileMode {
+	switch {
+	case e.Subdir:
+		return fs.ModeDir
+	case e.Symlink:
+		return fs.ModeSymlink
+	}
+
+	return e.Special
+}
+
+func (e cachedEntry) Name() string               { return e.Base }
+func (e cachedEntry) IsDir() bool                { return e.Subdir }
+func (e cachedEntry) Info() (fs.FileInfo, error) { return e, nil }
+func (e cachedEntry) Size() int64                { return e.Length }
+func (e cachedEntry) Mode() fs.FileMode          { return e.Type() | e.Perm }
+func (e cachedEntry) ModTime() time.Time         { return e.Modified }
+func (e cachedEntry) Sys() any                   { return nil }
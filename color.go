@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+
+	colorBandsDefault = "1GB=yellow,10GB=red"
+)
+
+var ansiColors = map[string]string{
+	"black":   "\x1b[30m",
+	"red":     "\x1b[31m",
+	"green":   "\x1b[32m",
+	"yellow":  "\x1b[33m",
+	"blue":    "\x1b[34m",
+	"magenta": "\x1b[35m",
+	"cyan":    "\x1b[36m",
+	"white":   "\x1b[37m",
+	"bold":    "\x1b[1m",
+}
+
+type colorBand struct {
+	threshold int64
+	code      string
+}
+
+// colorizer paints text depending on the size it describes, a nil colorizer leaves the
+// text as is.
+type colorizer struct {
+	// bands are sorted by threshold in descending order.
+	bands []colorBand
+}
+
+// newColorizer parses bands in the form of SIZE=COLOR[,SIZE=COLOR...].
+func newColorizer(bands string) (*colorizer, error) {
+	c := &colorizer{}
+
+	for _, band := range strings.Split(bands, ",") {
+		size, color, ok := strings.Cut(strings.TrimSpace(band), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid color band '%v', expected SIZE=COLOR", band)
+		}
+
+		threshold, err := humanize.ParseBigBytes(size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size in color band '%v': %v", band, err)
+		}
+
+		code, ok := ansiColors[color]
+		if !ok {
+			return nil, fmt.Errorf("unknown color '%v'", color)
+		}
+
+		c.bands = append(c.bands, colorBand{threshold: threshold.Int64(), code: code})
+	}
+
+	sort.Slice(c.bands, func(i, j int) bool {
+		return c.bands[i].threshold > c.bands[j].threshold
+	})
+
+	return c, nil
+}
+
+func (c *colorizer) paint(size int64, s string) string {
+	if c == nil {
+		return s
+	}
+
+	for _, band := range c.bands {
+		if size > band.threshold {
+			return band.code + s + ansiReset
+		}
+	}
+
+	return s
+}
+
+// shouldColorize decides whether output to f should be colored according to mode.
+func shouldColorize(mode string, f *os.File) (bool, error) {
+	switch mode {
+	case colorAuto:
+		return isTerminal(f.Fd()), nil
+	case colorAlways:
+		return true, nil
+	case colorNever:
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid color mode '%v', expected one of: auto, always, never", mode)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+const (
+	// compressionSample is how much of a file is compressed to estimate how well all of
+	// it compresses, it is read in compressionChunks spread over the file.
+	compressionSample = 4 << 20
+	compressionChunks = 8
+
+	// compressionMaxFiles limits the number of files sampled, the largest ones are.
+	compressionMaxFiles = 100
+)
+
+// flaggedFiles passes everything on to the reporter and keeps aside the reported files,
+// so that they can be looked into once the scan is over.
+type flaggedFiles struct {
+	reporter
+
+	mu    sync.Mutex
+	files []*entry
+}
+
+func (f *flaggedFiles) report(e *entry) {
+	if e.typ == entryFile {
+		f.mu.Lock()
+		f.files = append(f.files, e)
+		f.mu.Unlock()
+	}
+
+	f.reporter.report(e)
+}
+
+// compressionEstimate is how large the file is estimated to be once compressed.
+type compressionEstimate struct {
+	e          *entry
+	compressed int64
+}
+
+// estimateCompression compresses a sample of the file with gzip and extrapolates the
+// ratio to the whole of it.
+func estimateCompression(e *entry) (compressionEstimate, error) {
+	f, err := os.Open(e.path)
+	if err != nil {
+		return compressionEstimate{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return compressionEstimate{}, err
+	}
+
+	out := &countingWriter{w: io.Discard}
+	gz := gzip.NewWriter(out)
+
+	var read int64
+	if size := info.Size(); size <= compressionSample {
+		read, err = io.Copy(gz, f)
+	} else {
+		chunk := int64(compressionSample / compressionChunks)
+		step := (size - chunk) / (compressionChunks - 1)
+
+		for i := int64(0); i < compressionChunks && err == nil; i++ {
+			var n int64
+			n, err = io.Copy(gz, io.NewSectionReader(f, i*step, chunk))
+			read += n
+		}
+	}
+	if err != nil {
+		return compressionEstimate{}, err
+	}
+	if err := gz.Close(); err != nil {
+		return compressionEstimate{}, err
+	}
+
+	if read == 0 {
+		return compressionEstimate{e: e, compressed: e.size}, nil
+	}
+
+	ratio := float64(out.n) / float64(read)
+	if ratio > 1 {
+		// compressing does not make anything larger, the file would be left as it is
+		ratio = 1
+	}
+
+	return compressionEstimate{e: e, compressed: int64(float64(e.size) * ratio)}, nil
+}
+
+// writeCompressionEstimates samples the largest reported files and prints how much
+// compressing them is estimated to save, the files saving the most first.
+func (v *visualiser) writeCompressionEstimates(w io.Writer) error {
+	files := v.flagged.files
+	if len(files) == 0 {
+		return nil
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].size > files[j].size })
+	if len(files) > compressionMaxFiles {
+		log.Printf("info: estimating compression of the %v largest of %v reported files", compressionMaxFiles, len(files))
+		files = files[:compressionMaxFiles]
+	}
+
+	var estimates []compressionEstimate
+	for _, e := range files {
+		est, err := estimateCompression(e)
+		if err != nil {
+			log.Printf("warning: could not estimate compression of %v: %v", v.display.path(e.path), err)
+			continue
+		}
+
+		estimates = append(estimates, est)
+	}
+
+	sort.SliceStable(estimates, func(i, j int) bool {
+		return estimates[i].e.size-estimates[i].compressed > estimates[j].e.size-estimates[j].compressed
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "File\tSize\tCompressed\tSaved")
+
+	var size, compressed int64
+	for _, est := range estimates {
+		size += est.e.size
+		compressed += est.compressed
+
+		fmt.Fprintf(
+			tw, "%v\t%v\t%v\t%v\n",
+			v.display.path(est.e.path), v.display.size(est.e.size), v.display.size(est.compressed), v.savings(est.e.size, est.compressed),
+		)
+	}
+
+	fmt.Fprintf(tw, "Total\t%v\t%v\t%v\n", v.display.size(size), v.display.size(compressed), v.savings(size, compressed))
+
+	return tw.Flush()
+}
+
+// savings tells how much compressing saves and which part of the size that is.
+func (v *visualiser) savings(size, compressed int64) string {
+	if size == 0 {
+		return v.display.size(0)
+	}
+
+	return fmt.Sprintf("%v (%.0f%%)", v.display.size(size-compressed), 100*float64(size-compressed)/float64(size))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// The scan database is the last scanned tree saved with -db in the msgpack format, the
+// query subcommand answers questions about it without touching the filesystem again.
+
+// saveDB replaces the database with the scanned tree.
+func saveDB(path string, root *entry) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeMsgpack(tmp, root); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func loadDB(path string) (*entry, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open database: %v", err)
+	}
+	defer in.Close()
+
+	root, err := readMsgpack(bufio.NewReader(in))
+	if err != nil {
+		return nil, fmt.Errorf("could not read database %v: %v", path, err)
+	}
+
+	return root, nil
+}
+
+// findEntry looks up the entry with the given path in the tree, it returns nil if the
+// path was not scanned.
+func findEntry(root *entry, path string) *entry {
+	path = filepath.Clean(path)
+
+	for e := root; e != nil; {
+		if filepath.Clean(e.path) == path {
+			return e
+		}
+
+		// the root of several scanned directories has no path of its own, so the
+		// children are looked at rather than the path of the parent
+		var next *entry
+		for _, child := range e.children {
+			if isWithin(path, filepath.Clean(child.path)) {
+				next = child
+				break
+			}
+		}
+		e = next
+	}
+
+	return nil
+}
+
+// reroot makes the entry the root of a tree of its own, the way it would be if it was
+// scanned itself.
+func reroot(e *entry, depth int) {
+	if depth == 0 {
+		e.parent = nil
+	}
+
+	e.depth = depth
+	for _, child := range e.children {
+		reroot(child, depth+1)
+	}
+}
+
+// queryMain implements the query subcommand, which reports entries of the scan saved
+// with -db as if the directory was scanned right now.
+func queryMain(args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s query -db FILE [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+
+	dbPath := fs.String("db", "", "database saved by a scan with -db")
+	under := fs.String("d", "", "report only entries under this directory")
+	sizeThreshold := fs.String("s", sizeThresholdDefault, "print directories and files exceeding this threshold (example: 100MB), or this percentage of the root (example: 5%)")
+	outFlags := addOutputFlags(fs)
+	fs.Parse(args)
+
+	if *dbPath == "" || fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	root, err := loadDB(*dbPath)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if *under != "" {
+		if root = findEntry(root, *under); root == nil {
+			log.Fatalf("directory %v was not scanned", *under)
+		}
+		if root.typ != entryDir {
+			log.Fatalf("%v is not a directory", *under)
+		}
+
+		reroot(root, 0)
+	}
+
+	reporter, out, err := outFlags.newReporter(nil)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	defer out.Close()
+
+	visualiser, err := newVisualiser(visualiserOptions{sizeThreshold: *sizeThreshold}, reporter)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if err := visualiser.replay(root); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// decodeMain implements the decode subcommand, which reads a scan saved with
+// -format msgpack and renders it in any other output format.
+func decodeMain(args []string) {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s decode [flags] FILE\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+
+	sizeThreshold := fs.String("s", sizeThresholdDefault, "print directories and files exceeding this threshold (example: 100MB), or this percentage of the root (example: 5%)")
+	outFlags := addOutputFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	in, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("could not open scan: %v", err)
+	}
+	defer in.Close()
+
+	root, err := readMsgpack(bufio.NewReader(in))
+	if err != nil {
+		log.Fatalf("could not decode scan %v: %v", fs.Arg(0), err)
+	}
+
+	reporter, out, err := outFlags.newReporter(nil)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	defer out.Close()
+
+	visualiser, err := newVisualiser(visualiserOptions{sizeThreshold: *sizeThreshold}, reporter)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if err := visualiser.replay(root); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type entryType int
+
+const (
+	entryFile entryType = iota
+	entryDir
+)
+
+func (t entryType) String() string {
+	switch t {
+	case entryFile:
+		return "file"
+	case entryDir:
+		return "dir"
+	default:
+		return "unknown"
+	}
+}
+
+func parseEntryType(s string) (entryType, error) {
+	switch s {
+	case "file":
+		return entryFile, nil
+	case "dir":
+		return entryDir, nil
+	default:
+		return 0, fmt.Errorf("unknown entry type '%v'", s)
+	}
+}
+
+// entry is a single file or directory met during the scan. For directories size is the
+// cumulative size of all the contents.
+type entry struct {
+	path   string
+	name   string
+	typ    entryType
+	size   int64
+	mtime  time.Time
+	depth  int
+	parent *entry
+
+	// otherSize is the disk usage if size is the apparent size and the other way round.
+	otherSize int64
+
+	// shared is the part of the disk usage in extents shared with other files, it is only
+	// found out with -shared.
+	shared int64
+
+	// streams is the size of the alternate data streams of a file, it is only found out
+	// with -streams.
+	streams int64
+
+	// atime is the time a file was last read, it is only found out with
+	// -not-accessed-since.
+	atime time.Time
+
+	// mode is the mode of a file, it is only kept with -perm.
+	mode fs.FileMode
+
+	// sparse is set for files taking far less space on disk than their apparent sizes.
+	sparse bool
+
+	// target is the path the symbolic link the entry was reached by points to, it is
+	// only known for the links followed with -L.
+	target string
+
+	// mount is the file system mounted at the directory, nil if it is not a mount point.
+	mount *fileSystem
+
+	// files and dirs are the numbers of files and directories within the directory at
+	// any depth.
+	files int64
+	dirs  int64
+
+	// changed is set for directories modified while being scanned, their sizes are
+	// possibly stale.
+	changed bool
+
+	// only is set for entries whose paths match one of the -only regexps and for
+	// everything within them, nothing else is counted.
+	only bool
+
+	// excluded is set for directories left out by -exclude that are looked into for what
+	// -include patterns given after it bring back, nothing else within them is counted.
+	excluded bool
+
+	// incomplete is set for directories some of whose contents were left out of the
+	// scan, by -exclude, -only, -owner, -i, -skip-hidden or ignore files, as skipped mount
+	// points, snapshots or directories reached before, or as they could not be read, their
+	// sizes do not tell how much deleting them would free.
+	incomplete bool
+
+	// ignores are the rules of the ignore files applying to the contents of the
+	// directory, .gitignore and .ignore files are only read with -respect-gitignore.
+	ignores *ignoreList
+
+	// flagged is set if the entry exceeds the size threshold.
+	flagged bool
+
+	// shown is set for the entries the tree formats render, the flagged ones and the
+	// directories leading to them, which -max, -type f or -top may leave unflagged.
+	shown bool
+
+	// children is populated only if the reporter needs the whole tree.
+	children []*entry
+}
+
+// detach removes the entry from the tree, sizes of all its ancestors are decreased
+// accordingly.
+func (e *entry) detach() {
+	if e.parent == nil {
+		return
+	}
+
+	files, dirs := e.files, e.dirs
+	if e.typ == entryDir {
+		dirs++
+	} else {
+		files++
+	}
+
+	for p := e.parent; p != nil; p = p.parent {
+		p.size -= e.size
+		p.otherSize -= e.otherSize
+		p.shared -= e.shared
+		p.files -= files
+		p.dirs -= dirs
+	}
+
+	children := e.parent.children
+	for i, child := range children {
+		if child == e {
+			e.parent.children = append(children[:i], children[i+1:]...)
+			break
+		}
+	}
+
+	e.parent = nil
+}
+
+// account adds the sizes of the child to the directory and counts it.
+func (dir *entry) account(child *entry) {
+	dir.size += child.size
+	dir.otherSize += child.otherSize
+	dir.shared += child.shared
+
+	dir.count(child)
+}
+
+// count adds the child together with its contents to the numbers of files and
+// directories within the directory.
+func (dir *entry) count(child *entry) {
+	dir.files += child.files
+	dir.dirs += child.dirs
+
+	if child.typ == entryDir {
+		dir.dirs++
+	} else {
+		dir.files++
+	}
+}
+
+// childPath is filepath.Join of a clean directory path and a name read from it, it does
+// not pay for cleaning the result.
+func childPath(dir, name string) string {
+	switch {
+	case dir == ".":
+		return name
+	case strings.HasSuffix(dir, string(filepath.Separator)):
+		return dir + name
+	}
+
+	return dir + string(filepath.Separator) + name
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseExts parses a comma-separated list of extensions, with or without leading dots,
+// into the lower-cased suffixes the names of files are matched against. Extensions may
+// have several parts, like tar.gz.
+func parseExts(list string) ([]string, error) {
+	var exts []string
+
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+		if ext == "" {
+			continue
+		}
+		if strings.ContainsAny(ext, `/\`) {
+			return nil, fmt.Errorf("invalid extension '%v'", ext)
+		}
+
+		exts = append(exts, "."+strings.ToLower(ext))
+	}
+
+	return exts, nil
+}
+
+// hasExt tells whether the name ends with one of the extensions, whatever the case. A
+// name made of the extension alone, like .gz, does not have it.
+func hasExt(name string, exts []string) bool {
+	name = strings.ToLower(name)
+
+	for _, ext := range exts {
+		if len(name) > len(ext) && strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isReportedExt tells whether a file with this name may be reported given -ext and
+// -not-ext, directories are reported whatever their names.
+func (v *visualiser) isReportedExt(e *entry) bool {
+	if e.typ != entryFile {
+		return true
+	}
+
+	return (len(v.exts) == 0 || hasExt(e.name, v.exts)) && !hasExt(e.name, v.notExts)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const (
+	fsIOCFiemap = 0xc020660b
+
+	fiemapExtentLast   = 0x1
+	fiemapExtentShared = 0x2000
+
+	// fiemapBatch is the number of extents asked for at once
+	fiemapBatch = 64
+)
+
+type fiemapExtent struct {
+	logical  uint64
+	physical uint64
+	length   uint64
+	_        [2]uint64
+	flags    uint32
+	_        [3]uint32
+}
+
+type fiemap struct {
+	start         uint64
+	length        uint64
+	flags         uint32
+	mappedExtents uint32
+	extentCount   uint32
+	_             uint32
+	extents       [fiemapBatch]fiemapExtent
+}
+
+// sharedSize returns how much of the file is stored in extents shared with other files,
+// such as reflink copies, deduplicated files or snapshots. ok is false if the file system
+// cannot tell.
+func sharedSize(path string) (shared int64, ok bool) {
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_NOFOLLOW|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return 0, false
+	}
+	defer syscall.Close(fd)
+
+	var fm fiemap
+	for start := uint64(0); ; {
+		fm = fiemap{start: start, length: ^uint64(0), extentCount: fiemapBatch}
+
+		_, _, errno := syscall.Syscall(
+			syscall.SYS_IOCTL, uintptr(fd), fsIOCFiemap, uintptr(unsafe.Pointer(&fm)),
+		)
+		if errno != 0 {
+			return 0, false
+		}
+		if fm.mappedExtents == 0 {
+			return shared, true
+		}
+
+		for _, e := range fm.extents[:fm.mappedExtents] {
+			if e.flags&fiemapExtentShared != 0 {
+				shared += int64(e.length)
+			}
+		}
+
+		last := fm.extents[fm.mappedExtents-1]
+		if last.flags&fiemapExtentLast != 0 {
+			return shared, true
+		}
+
+		start = last.logical + last.length
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package main
+
+func sharedSize(path string) (shared int64, ok bool) {
+	return 0, false
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !freebsd
+
+package main
+
+import "os"
+
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+func fileIDOf(info os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
+
+func linkedFileID(info os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
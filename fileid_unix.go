@@ -0,0 +1,39 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID identifies a file regardless of the path it is reached by.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+func fileIDOf(info os.FileInfo) (fileID, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
+
+// linkedFileID returns the ID of a file with several hard links, cached entries keep it
+// for such files.
+func linkedFileID(info os.FileInfo) (fileID, bool) {
+	switch i := info.(type) {
+	case cachedEntry:
+		return fileID{dev: i.Dev, ino: i.Ino}, i.Ino != 0
+	}
+
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st.Nlink < 2 {
+		return fileID{}, false
+	}
+
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
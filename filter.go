@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// glob is a shell-style pattern: * and ? match within a single path component, **
+// matches any number of them. A pattern without slashes is matched against names, one
+// with slashes against the trailing components of paths, or whole paths if it starts
+// with a slash.
+type glob struct {
+	re       *regexp.Regexp
+	basename bool
+}
+
+func compileGlob(pattern string, ignoreCase bool) (*glob, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	basename := !strings.Contains(pattern, "/")
+
+	expr := globToRegexp(pattern)
+	switch {
+	case basename || strings.HasPrefix(pattern, "/"):
+		expr = "^" + expr + "$"
+	default:
+		expr = "(^|/)" + expr + "$"
+	}
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%v': %v", pattern, err)
+	}
+
+	return &glob{re: re, basename: basename}, nil
+}
+
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case pattern[i:] == "/**":
+			b.WriteString("(/.*)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return b.String()
+}
+
+// match tells whether the entry of the directory matches the pattern, the path of the
+// entry is only built if the pattern needs it.
+func (g *glob) match(dir, name string) bool {
+	if g.basename {
+		return g.re.MatchString(name)
+	}
+
+	return g.re.MatchString(filepath.ToSlash(childPath(dir, name)))
+}
+
+// filterPattern is a pattern of -exclude or -include as given.
+type filterPattern struct {
+	pattern string
+	include bool
+}
+
+// filterRule is a compiled -exclude or -include pattern.
+type filterRule struct {
+	*glob
+	include bool
+}
+
+// filterFlag adds the patterns of -exclude or -include to the list shared by both, so
+// that they are kept in the order given.
+type filterFlag struct {
+	patterns *[]filterPattern
+	include  bool
+}
+
+func (f filterFlag) String() string {
+	if f.patterns == nil {
+		return ""
+	}
+
+	var patterns []string
+	for _, p := range *f.patterns {
+		if p.include == f.include {
+			patterns = append(patterns, p.pattern)
+		}
+	}
+
+	return strings.Join(patterns, ",")
+}
+
+func (f filterFlag) Set(value string) error {
+	*f.patterns = append(*f.patterns, filterPattern{pattern: value, include: f.include})
+	return nil
+}
+
+// isExcluded tells whether the entry of the directory is left out: the last of the
+// -exclude and -include patterns matching it decides, the entries matching none are left
+// out if their directory is.
+func (v *visualiser) isExcluded(dir, name string, dirExcluded bool) bool {
+	for i := len(v.filters) - 1; i >= 0; i-- {
+		if v.filters[i].match(dir, name) {
+			return !v.filters[i].include
+		}
+	}
+
+	return dirExcluded
+}
+
+// readPatterns reads the patterns listed one per line in the file the way rsync and tar
+// take them, blank lines and lines starting with # or ; are skipped.
+func readPatterns(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		patterns = append(patterns, line)
+	}
+
+	return patterns, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		ignoreCase bool
+		dir, name  string
+		want       bool
+	}{
+		// patterns without slashes match names wherever they are
+		{pattern: "*.log", dir: "/var/log", name: "syslog.log", want: true},
+		{pattern: "*.log", dir: "/var/log", name: "syslog.log.1", want: false},
+		{pattern: "*.log", dir: "/var", name: "log", want: false},
+		{pattern: "?.txt", dir: "/tmp", name: "a.txt", want: true},
+		{pattern: "?.txt", dir: "/tmp", name: "ab.txt", want: false},
+		{pattern: "node_modules", dir: "/src/app", name: "node_modules", want: true},
+		{pattern: "node_modules", dir: "/src/app", name: "node_modules2", want: false},
+
+		// character classes
+		{pattern: "[ab].bin", dir: "/tmp", name: "a.bin", want: true},
+		{pattern: "[ab].bin", dir: "/tmp", name: "c.bin", want: false},
+		{pattern: "[!ab].bin", dir: "/tmp", name: "c.bin", want: true},
+		{pattern: "[!ab].bin", dir: "/tmp", name: "a.bin", want: false},
+		{pattern: "[a-c]*", dir: "/tmp", name: "core", want: true},
+		{pattern: "[a-c]*", dir: "/tmp", name: "dump", want: false},
+		{pattern: "[abc", dir: "/tmp", name: "[abc", want: true},
+		{pattern: `\*.bin`, dir: "/tmp", name: "*.bin", want: true},
+		{pattern: `\*.bin`, dir: "/tmp", name: "a.bin", want: false},
+
+		// patterns with slashes match the trailing components of paths
+		{pattern: "build/*.o", dir: "/src/build", name: "main.o", want: true},
+		{pattern: "build/*.o", dir: "/src/build/sub", name: "main.o", want: false},
+		{pattern: "build/*.o", dir: "/src/rebuild", name: "main.o", want: false},
+		{pattern: "*/cache", dir: "/home/user", name: "cache", want: true},
+
+		// a leading slash anchors the pattern at the start of the path
+		{pattern: "/tmp/*", dir: "/tmp", name: "x", want: true},
+		{pattern: "/tmp/*", dir: "/var/tmp", name: "x", want: false},
+		{pattern: "/tmp/*", dir: "/tmp/sub", name: "x", want: false},
+
+		// ** crosses components, * and ? do not
+		{pattern: "**/cache", dir: "/home/user/.local", name: "cache", want: true},
+		{pattern: "/home/**/cache", dir: "/home", name: "cache", want: true},
+		{pattern: "/home/**/cache", dir: "/home/a/b", name: "cache", want: true},
+		{pattern: "/home/**/cache", dir: "/var/a", name: "cache", want: false},
+		{pattern: "/var/**", dir: "/var/lib/apt", name: "lists", want: true},
+		{pattern: "/var/**", dir: "/", name: "var", want: true},
+		{pattern: "/var/**", dir: "/", name: "variant", want: false},
+		{pattern: "/src/*/x", dir: "/src/a/b", name: "x", want: false},
+		{pattern: "/src/a**", dir: "/src/abc/d", name: "e", want: true},
+
+		{pattern: "*.JPG", dir: "/photos", name: "a.jpg", want: false},
+		{pattern: "*.JPG", ignoreCase: true, dir: "/photos", name: "a.jpg", want: true},
+	}
+
+	for _, tt := range tests {
+		g, err := compileGlob(tt.pattern, tt.ignoreCase)
+		if err != nil {
+			t.Errorf("compileGlob(%q) failed: %v", tt.pattern, err)
+			continue
+		}
+
+		if got := g.match(tt.dir, tt.name); got != tt.want {
+			t.Errorf("%q matching %v in %v = %v, want %v", tt.pattern, tt.name, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestCompileGlobEmpty(t *testing.T) {
+	if _, err := compileGlob("", false); err == nil {
+		t.Errorf("compileGlob(\"\") succeeded, want an error")
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"*.go", `[^/]*\.go`},
+		{"a?c", `a[^/]c`},
+		{"**/x", `(.*/)?x`},
+		{"x/**", `x(/.*)?`},
+		{"a**b", `a.*b`},
+		{"[!a-c]", `[^a-c]`},
+		{`[\]`, `[\\]`},
+		{"[", `\[`},
+		{`\?`, `\?`},
+		{"a+b", `a\+b`},
+	}
+
+	for _, tt := range tests {
+		if got := globToRegexp(tt.pattern); got != tt.want {
+			t.Errorf("globToRegexp(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
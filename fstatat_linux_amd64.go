@@ -0,0 +1,23 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func fstatat(dirfd int, name string, st *syscall.Stat_t, flags int) error {
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.Syscall6(
+		syscall.SYS_NEWFSTATAT,
+		uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(st)), uintptr(flags), 0, 0,
+	)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -0,0 +1,7 @@
+package main
+
+import "syscall"
+
+func fstatat(dirfd int, name string, st *syscall.Stat_t, flags int) error {
+	return syscall.Fstatat(dirfd, name, st, flags)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// gitignoreFileNames are the files listing what is to be left out of the directory they
+// are found in, the way git and ripgrep read them.
+var gitignoreFileNames = []string{".gitignore", ".ignore"}
+
+// ownIgnoreFileName is the file in the same format read whatever -respect-gitignore is,
+// so that whoever owns a directory can mark parts of it as not worth scanning.
+const ownIgnoreFileName = ".spacevisignore"
+
+// ignoreRule is a line of an ignore file.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+
+	// anchored rules are matched against paths relative to the directory of the
+	// ignore file, the rest against names.
+	anchored bool
+}
+
+// ignoreList holds the rules of the ignore files of a directory, the rules of the
+// directories above it come next.
+type ignoreList struct {
+	prefix string
+	rules  []ignoreRule
+	parent *ignoreList
+}
+
+// loadIgnores reads the ignore files of the given names among the entries of the
+// directory, it returns the list of the parent if there are none.
+func loadIgnores(dir string, entries []listedEntry, names []string, parent *ignoreList) *ignoreList {
+	var rules []ignoreRule
+
+	for _, le := range entries {
+		for _, name := range names {
+			if le.Name() == name && le.Type().IsRegular() {
+				rules = append(rules, readIgnoreFile(childPath(dir, name))...)
+			}
+		}
+	}
+
+	if len(rules) == 0 {
+		return parent
+	}
+
+	return &ignoreList{prefix: childPath(dir, ""), rules: rules, parent: parent}
+}
+
+func readIgnoreFile(path string) []ignoreRule {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("warning: could not read %v: %v", path, err)
+		return nil
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		rule.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		re, err := regexp.Compile("^" + globToRegexp(line) + "$")
+		if err != nil {
+			log.Printf("warning: skipping pattern '%v' of %v: %v", line, path, err)
+			continue
+		}
+		rule.re = re
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// ignores tells whether the entry of the directory is ignored: the rules of the nearest
+// ignore files take precedence, the last matching rule of a file wins.
+func (l *ignoreList) ignores(dir, name string, isDir bool) bool {
+	path := childPath(dir, name)
+
+	for ; l != nil; l = l.parent {
+		rel := filepath.ToSlash(strings.TrimPrefix(path, l.prefix))
+
+		for i := len(l.rules) - 1; i >= 0; i-- {
+			rule := &l.rules[i]
+			if rule.dirOnly && !isDir {
+				continue
+			}
+
+			subject := name
+			if rule.anchored {
+				subject = rel
+			}
+
+			if rule.re.MatchString(subject) {
+				return !rule.negate
+			}
+		}
+	}
+
+	return false
+}
@@ -2,4 +2,7 @@ module github.com/gibsn/space_visualiser
 
 go 1.22
 
-require github.com/dustin/go-humanize v1.0.1
+require (
+	github.com/dustin/go-humanize v1.0.1
+	golang.org/x/text v0.22.0
+)
@@ -0,0 +1,9 @@
+package main
+
+import "strings"
+
+// isHidden tells whether the entry is hidden: dot-files and dot-directories are hidden
+// everywhere, on Windows files can be hidden by an attribute as well.
+func isHidden(le listedEntry) bool {
+	return strings.HasPrefix(le.Name(), ".") || hasHiddenAttribute(le)
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package main
+
+func hasHiddenAttribute(le listedEntry) bool {
+	return false
+}
@@ -0,0 +1,17 @@
+package main
+
+import "syscall"
+
+func hasHiddenAttribute(le listedEntry) bool {
+	info := le.info
+	if info == nil {
+		var err error
+		if info, err = le.Info(); err != nil {
+			return false
+		}
+	}
+
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+
+	return ok && attrs.FileAttributes&syscall.FILE_ATTRIBUTE_HIDDEN != 0
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// sortBySizeHint orders the entries of the directory so that the probably largest ones
+// come first. Sizes of files are known, sizes of directories are guessed from the cache
+// populated by the previous scan or, failing that, from the space taken by the
+// directories themselves, which grows with the number of their entries.
+func (v *visualiser) sortBySizeHint(path string, entries []listedEntry) {
+	hints := make(map[string]int64, len(entries))
+	for _, le := range entries {
+		if le.info == nil {
+			continue
+		}
+
+		switch {
+		case le.Type().IsRegular():
+			hints[le.Name()] = le.info.Size()
+
+		case le.Type().IsDir():
+			if v.cache != nil {
+				if size, ok := v.cache.sizeHint(filepath.Join(path, le.Name())); ok {
+					hints[le.Name()] = size
+					continue
+				}
+			}
+
+			if size, ok := allocatedSize(le.info); ok {
+				hints[le.Name()] = size
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return hints[entries[i].Name()] > hints[entries[j].Name()]
+	})
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// prefetchPerJob limits how many directory listings may be read ahead of the scan for
+// each job, so that the readers cannot outrun the scan by the whole tree.
+const prefetchPerJob = 16
+
+// maxOpenDirs limits how many directories are kept open to open their subdirectories
+// relative to them, the rest are opened by their paths.
+const maxOpenDirs = 256
+
+// listedEntry is a directory entry together with the results of stat-ing it.
+type listedEntry struct {
+	os.DirEntry
+
+	info    os.FileInfo
+	infoErr error
+
+	// sub is the listing of the directory being read in background, or nil if it
+	// has to be read when the scan gets to it.
+	sub *dirListing
+}
+
+// dirListing holds the contents of a directory, possibly being read by another
+// goroutine until done is closed.
+type dirListing struct {
+	entries []listedEntry
+	err     error
+
+	// info is the result of stat-ing the directory itself, nil if it was not needed
+	info os.FileInfo
+
+	// dir is the directory kept open for reading its subdirectories relative to it,
+	// nil if it was closed once read.
+	dir *openDir
+
+	done chan struct{}
+}
+
+// openDir is a directory opened by a reader supporting reading relative to it.
+type openDir struct {
+	fd int
+}
+
+// defaultJobs picks the number of directories to read concurrently for the given
+// root: spinning disks suffer from seeks, so they are read with little concurrency.
+func defaultJobs(root string) int {
+	if rotational, ok := isRotational(root); ok && rotational {
+		return 2
+	}
+
+	return runtime.GOMAXPROCS(0)
+}
+
+// readDir reads the directory and stats its entries, it also schedules reading the
+// subdirectories in background if there are free jobs. The directory is opened relative
+// to its parent if it is open. The info of the directory is nil if it has not been
+// stat-ed yet.
+func (v *visualiser) readDir(
+	ctx context.Context, path string, parent *openDir, info os.FileInfo,
+) *dirListing {
+	if err := ctx.Err(); err != nil {
+		return &dirListing{err: err}
+	}
+
+	var mtime time.Time
+	if info != nil {
+		mtime = info.ModTime()
+	}
+
+	if v.cache != nil {
+		if cached := v.cache.lookup(path, mtime); cached != nil {
+			l := v.readCachedDir(ctx, path, cached)
+			l.info = info
+
+			return l
+		}
+	}
+
+	v.throttle.wait()
+
+	read := readDirStat
+	if v.rawReadDir {
+		read = readDirRaw
+	}
+
+	// subdirectories have to be stat-ed to look them up in the cache or to guess their
+	// sizes, otherwise the mtime of a directory is found out once it is opened
+	mode := dirReadMode{
+		statSubdirs: v.cache != nil || v.largeFirst,
+		statSelf:    info == nil && (v.needMtime || v.dedup || v.checkChanges || v.dirSizes),
+	}
+
+	// a read left running after a timeout could outlive the parent it is relative to
+	if v.dirTimeout > 0 {
+		parent = nil
+	} else if v.openDirs.Add(1) <= maxOpenDirs {
+		mode.keepOpen = true
+	} else {
+		v.openDirs.Add(-1)
+	}
+
+	l := &dirListing{info: info}
+	entries, selfInfo, dir, err := v.readWithTimeout(path, parent, mode, read)
+	if mode.keepOpen && dir == nil {
+		v.openDirs.Add(-1)
+	}
+	if err != nil {
+		l.err = err
+		return l
+	}
+
+	l.entries = entries
+	l.dir = dir
+	if selfInfo != nil {
+		l.info = selfInfo
+	}
+
+	for i := range l.entries {
+		if le := &l.entries[i]; le.Type().IsDir() {
+			v.prefetchSubdir(ctx, path, l.dir, le)
+		}
+	}
+
+	if v.cache != nil {
+		v.cache.store(path, mtime, l.entries)
+	}
+
+	return l
+}
+
+// dirReadMode tells what has to be stat-ed besides regular files, which are always
+// stat-ed to find out their sizes.
+type dirReadMode struct {
+	statSubdirs bool
+	statSelf    bool
+
+	// keepOpen asks to return the directory open if the reader can read relative to it
+	keepOpen bool
+}
+
+// dirReader reads the directory, relative to the parent if it is not nil. The info of
+// the directory itself is returned only if the mode asks for it, the open directory only
+// if the mode asks for it and the reader supports it.
+type dirReader func(
+	path string, parent *openDir, mode dirReadMode,
+) ([]listedEntry, os.FileInfo, *openDir, error)
+
+// readWithTimeout gives up reading the directory after dirTimeout, so that a hung
+// network mount does not block the whole scan. The read itself cannot be interrupted
+// and is left running in background.
+func (v *visualiser) readWithTimeout(
+	path string, parent *openDir, mode dirReadMode, read dirReader,
+) ([]listedEntry, os.FileInfo, *openDir, error) {
+	if v.dirTimeout <= 0 {
+		return read(path, parent, mode)
+	}
+
+	// the read may be abandoned, nobody would close the directory then
+	mode.keepOpen = false
+
+	type result struct {
+		entries []listedEntry
+		info    os.FileInfo
+		err     error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		entries, info, _, err := read(path, parent, mode)
+		done <- result{entries, info, err}
+	}()
+
+	timer := time.NewTimer(v.dirTimeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-done:
+		return res.entries, res.info, nil, res.err
+	case <-timer.C:
+		return nil, nil, nil, fmt.Errorf("timed out after %v", v.dirTimeout)
+	}
+}
+
+// readDirStat reads the directory and stats its regular files, everything else is
+// stat-ed only if the mode asks for it. The directory is always opened by its path.
+func readDirStat(
+	path string, parent *openDir, mode dirReadMode,
+) ([]listedEntry, os.FileInfo, *openDir, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+
+	var info os.FileInfo
+	if mode.statSelf {
+		// the info is best effort, the contents are what matters
+		info, _ = f.Stat()
+	}
+
+	dirEntries, err := f.ReadDir(-1)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	sort.Slice(dirEntries, func(i, j int) bool { return dirEntries[i].Name() < dirEntries[j].Name() })
+
+	entries := make([]listedEntry, len(dirEntries))
+	for i, dirEntry := range dirEntries {
+		le := &entries[i]
+		le.DirEntry = dirEntry
+
+		if dirEntry.Type().IsRegular() || dirEntry.Type().IsDir() && mode.statSubdirs {
+			le.info, le.infoErr = dirEntry.Info()
+		}
+	}
+
+	return entries, info, nil, nil
+}
+
+// readCachedDir makes a listing out of the cached one, only subdirectories have to be
+// stat-ed to find out whether they changed.
+func (v *visualiser) readCachedDir(ctx context.Context, path string, cached []cachedEntry) *dirListing {
+	l := &dirListing{entries: make([]listedEntry, len(cached))}
+
+	for i, c := range cached {
+		le := &l.entries[i]
+		le.DirEntry = c
+
+		if c.Subdir {
+			le.info, le.infoErr = os.Lstat(filepath.Join(path, c.Base))
+			v.prefetchSubdir(ctx, path, nil, le)
+		} else {
+			le.info = c
+		}
+	}
+
+	return l
+}
+
+func (v *visualiser) prefetchSubdir(ctx context.Context, path string, dir *openDir, le *listedEntry) {
+	subPath := filepath.Join(path, le.Name())
+	if v.shouldSkipDir(subPath) || v.skipHidden && isHidden(*le) || v.isExcluded(filepath.Clean(path), le.Name(), false) && !v.hasIncludes {
+		return
+	}
+
+	// mount points skipped by the scan are not read ahead either
+	if fs := v.mountPointAt(subPath); fs != nil && v.mountSkipReason(subPath, fs) != "" {
+		return
+	}
+
+	le.sub = v.prefetch(ctx, subPath, dir, le.info)
+}
+
+// prefetch starts reading the directory in background, it returns nil if too many
+// listings are read ahead already. The parent is not closed until the read is done.
+func (v *visualiser) prefetch(
+	ctx context.Context, path string, parent *openDir, info os.FileInfo,
+) *dirListing {
+	if v.memoryLow.Load() {
+		return nil
+	}
+
+	select {
+	case v.prefetched <- struct{}{}:
+	default:
+		return nil
+	}
+
+	l := &dirListing{done: make(chan struct{})}
+
+	go func() {
+		v.readers <- struct{}{}
+		res := v.readDir(ctx, path, parent, info)
+		<-v.readers
+
+		l.entries, l.info, l.dir, l.err = res.entries, res.info, res.dir, res.err
+		close(l.done)
+	}()
+
+	return l
+}
+
+// listing returns the contents of the directory, waiting for it to be read in
+// background if it was prefetched.
+func (v *visualiser) listing(
+	ctx context.Context, path string, parent *openDir, info os.FileInfo, l *dirListing,
+) *dirListing {
+	if l == nil {
+		return v.readDir(ctx, path, parent, info)
+	}
+
+	<-l.done
+	<-v.prefetched
+
+	return l
+}
+
+// discard drops the subdirectories read ahead of the scan that is not going to get to
+// them.
+func (v *visualiser) discard(entries []listedEntry) {
+	for _, le := range entries {
+		if le.sub == nil {
+			continue
+		}
+
+		<-le.sub.done
+		<-v.prefetched
+
+		v.discard(le.sub.entries)
+		v.closeDir(le.sub)
+	}
+}
+
+// closeDir closes the directory kept open with the listing once the subdirectories read
+// relative to it in background are read.
+func (v *visualiser) closeDir(l *dirListing) {
+	if l.dir == nil {
+		return
+	}
+
+	for _, le := range l.entries {
+		if le.sub != nil {
+			<-le.sub.done
+		}
+	}
+
+	l.dir.close()
+	l.dir = nil
+	v.openDirs.Add(-1)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	liveInterval  = 500 * time.Millisecond
+	liveMinWidth  = 20
+	liveMinHeight = 6
+	// terminal cells are about twice as tall as they are wide
+	liveCellAspect = 2
+)
+
+var liveBackgrounds = []string{
+	"\x1b[44m", "\x1b[42m", "\x1b[45m", "\x1b[46m", "\x1b[43m", "\x1b[41m",
+}
+
+// liveTreemap draws a treemap of the top-level entries of the scanned directory on a
+// terminal and redraws it as the scan goes. On terminals too small for a treemap only
+// the status line is shown. Everything written during the scan is postponed until the
+// scan is over.
+type liveTreemap struct {
+	*progress
+
+	mu       sync.Mutex
+	sizes    map[string]int64
+	scanning bool
+	pending  []*liveTreemapWriter
+}
+
+func newLiveTreemap(w io.Writer, d display) *liveTreemap {
+	return &liveTreemap{
+		progress: newProgress(w, d),
+		sizes:    make(map[string]int64),
+		scanning: true,
+	}
+}
+
+func (l *liveTreemap) start() {
+	l.started = time.Now()
+	l.done = make(chan struct{})
+	l.wg.Add(1)
+
+	fmt.Fprint(l.w, ansiAltScreenOn+ansiCursorHide)
+
+	go func() {
+		defer l.wg.Done()
+
+		ticker := time.NewTicker(liveInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-l.done:
+				return
+			case <-ticker.C:
+				l.draw()
+			}
+		}
+	}()
+}
+
+func (l *liveTreemap) stop() {
+	close(l.done)
+	l.wg.Wait()
+
+	fmt.Fprint(l.w, ansiCursorShow+ansiAltScreenOff)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.scanning = false
+
+	for _, w := range l.pending {
+		w.flush()
+	}
+
+	l.pending = nil
+}
+
+func (l *liveTreemap) addFile(e *entry) {
+	l.progress.addFile(e)
+
+	top := e
+	for top.parent != nil && top.parent.parent != nil {
+		top = top.parent
+	}
+
+	if top.parent == nil {
+		return
+	}
+
+	l.mu.Lock()
+	l.sizes[top.name] += e.size
+	l.mu.Unlock()
+}
+
+func (l *liveTreemap) writer(w io.Writer) io.Writer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lw := &liveTreemapWriter{l: l, w: w}
+	l.pending = append(l.pending, lw)
+
+	return lw
+}
+
+func (l *liveTreemap) draw() {
+	width, height := tuiDefaultWidth, tuiDefaultRows
+	if w, h, err := terminalSize(os.Stderr.Fd()); err == nil && w > 0 && h > 0 {
+		width, height = w, h
+	}
+
+	status := truncate(l.status(), width)
+
+	if width < liveMinWidth || height < liveMinHeight {
+		fmt.Fprint(l.w, ansiHome+ansiClearScreen+status)
+		return
+	}
+
+	l.mu.Lock()
+	names := make([]string, 0, len(l.sizes))
+	for name, size := range l.sizes {
+		if size > 0 {
+			names = append(names, name)
+		}
+	}
+	sizes := make(map[string]int64, len(names))
+	for _, name := range names {
+		sizes[name] = l.sizes[name]
+	}
+	l.mu.Unlock()
+
+	sort.Slice(names, func(i, j int) bool {
+		return sizes[names[i]] > sizes[names[j]]
+	})
+
+	rows := height - 1
+	grid := make([][]int, rows)
+	for i := range grid {
+		grid[i] = make([]int, width)
+		for j := range grid[i] {
+			grid[i][j] = -1
+		}
+	}
+	labelAt := make(map[[2]int]string)
+
+	total := int64(0)
+	for _, name := range names {
+		total += sizes[name]
+	}
+
+	if total > 0 {
+		area := treemapRect{0, 0, float64(width), float64(rows * liveCellAspect)}
+		areas := make([]float64, len(names))
+		for i, name := range names {
+			areas[i] = float64(sizes[name]) / float64(total) * area.w * area.h
+		}
+
+		for i, r := range squarify(areas, area) {
+			x0, x1 := int(r.x+0.5), int(r.x+r.w+0.5)
+			y0, y1 := int(r.y/liveCellAspect+0.5), int((r.y+r.h)/liveCellAspect+0.5)
+
+			for y := y0; y < y1 && y < rows; y++ {
+				for x := x0; x < x1 && x < width; x++ {
+					grid[y][x] = i
+				}
+			}
+
+			if x1 > x0 && y1 > y0 && y0 < rows {
+				labelAt[[2]int{y0, x0}] = truncate(names[i]+" "+l.size(sizes[names[i]]), x1-x0)
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString(ansiHome)
+
+	for y := 0; y < rows; y++ {
+		line := []rune(strings.Repeat(" ", width))
+
+		for x := 0; x < width; x++ {
+			if label, ok := labelAt[[2]int{y, x}]; ok {
+				copy(line[x:], []rune(label))
+			}
+		}
+
+		color := -2
+		for x := 0; x < width; x++ {
+			if grid[y][x] != color {
+				color = grid[y][x]
+				if color < 0 {
+					b.WriteString(ansiReset)
+				} else {
+					b.WriteString(liveBackgrounds[color%len(liveBackgrounds)])
+				}
+			}
+
+			b.WriteRune(line[x])
+		}
+
+		b.WriteString(ansiReset + "\r\n")
+	}
+
+	b.WriteString(status + ansiClearLine)
+
+	l.progress.mu.Lock()
+	l.w.Write(b.Bytes())
+	l.progress.mu.Unlock()
+}
+
+// liveTreemapWriter keeps everything written to it during the scan until flush is called.
+type liveTreemapWriter struct {
+	l   *liveTreemap
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (lw *liveTreemapWriter) Write(b []byte) (int, error) {
+	lw.l.mu.Lock()
+	defer lw.l.mu.Unlock()
+
+	if !lw.l.scanning {
+		return lw.w.Write(b)
+	}
+
+	return lw.buf.Write(b)
+}
+
+// flush writes out everything kept so far, lw.l.mu must be held.
+func (lw *liveTreemapWriter) flush() {
+	lw.w.Write(lw.buf.Bytes())
+	lw.buf.Reset()
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package main
+
+func rootPath(dir string) string {
+	return dir
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxShortPath is the length from which paths have to be given in the extended-length
+// form, directories are limited to MAX_PATH less 12 to leave room for 8.3 names.
+const maxShortPath = 248
+
+// rootPath turns the root into an absolute path: the os package gives long absolute
+// paths the \\?\ prefix lifting the MAX_PATH limit, relative ones would fail deep in
+// trees like node_modules.
+func rootPath(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+
+	return dir
+}
+
+// longPath returns the extended-length form of a long absolute path for the calls made
+// bypassing the os package.
+func longPath(path string) string {
+	if len(path) < maxShortPath || !filepath.IsAbs(path) || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+
+	if strings.HasPrefix(path, `\\`) {
+		return `\\?\UNC\` + path[2:]
+	}
+
+	return `\\?\` + path
+}
@@ -1,157 +1,589 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	"math/big"
 	"os"
-	"path/filepath"
-	"regexp"
+	"os/signal"
+	"strings"
+	"time"
+)
 
-	"github.com/dustin/go-humanize"
+const (
+	rootDirDefault            = "/"
+	sizeThresholdDefault      = "100MB"
+	inodesThresholdDefault    = "10000"
+	streamsThresholdDefault   = "10MB"
+	jobsDefault               = 0
+	checkpointIntervalDefault = time.Minute
+	formatDefault             = formatText
+	outputDefault             = ""
+	columnsDefault            = "path,size,type"
 )
 
+// exitInterrupted is the exit code of a scan interrupted by SIGINT, the same a shell
+// reports for a process killed by it.
+const exitInterrupted = 130
+
+// exit codes of a scan with -strict
 const (
-	rootDirDefault         = "/"
-	sizeThresholdDefault   = "100MB"
-	ignoreDirRegexpDefault = ""
+	exitExceeded = 1
+	exitFailed   = 2
 )
 
 func main() {
-	rootDir := flag.String("d", rootDirDefault, "directory to search")
-	sizeThreshold := flag.String("s", sizeThresholdDefault, "print directories and files exceeding this threshold (example: 100MB)")
-	ignoreDirRegexp := flag.String("i", ignoreDirRegexpDefault, "regexp of directories to ignore")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "decode":
+			decodeMain(os.Args[2:])
+			return
+		case "serve":
+			serveMain(os.Args[2:])
+			return
+		case "query":
+			queryMain(os.Args[2:])
+			return
+		case "bench":
+			benchMain(os.Args[2:])
+			return
+		}
+	}
+
+	scanFlags := addScanFlags(flag.CommandLine)
+	outFlags := addOutputFlags(flag.CommandLine)
+	profFlags := addProfileFlags(flag.CommandLine)
+	hideProgress := flag.Bool("no-progress", false, "do not show progress of the scan")
+	live := flag.Bool("live", false, "show a treemap of the scanned directory updating as the scan goes")
+	db := flag.String("db", "", "save the scanned tree to this file for the query subcommand")
+	stream := flag.Bool("stream", false, "keep memory usage bounded by printing entries as soon as they are found, only formats not keeping entries are allowed")
+	strict := flag.Bool("strict", false, fmt.Sprintf("exit with %v if the scan failed or any directory or file could not be read, with %v if anything exceeded the threshold", exitFailed, exitExceeded))
 	flag.Parse()
 
-	visualiser, err := newVisualiser(*sizeThreshold, *ignoreDirRegexp)
+	// log.Fatalf exits with 1, which -strict reserves for entries exceeding the threshold
+	fatalf := log.Fatalf
+	if *strict {
+		fatalf = func(format string, v ...any) {
+			log.Printf(format, v...)
+			os.Exit(exitFailed)
+		}
+	}
+
+	if *stream {
+		if format := outFlags.format(); !streamingFormats[format] {
+			fatalf("format '%v' keeps entries in memory and cannot be used with -stream", format)
+		}
+		if *live {
+			fatalf("-live postpones the output and cannot be used with -stream")
+		}
+		if *outFlags.sortBy != "" {
+			fatalf("-sort postpones the output and cannot be used with -stream")
+		}
+
+		// the pager keeps the whole output in memory
+		*outFlags.noPager = true
+	}
+
+	var p scanProgress
+	switch {
+	case *live:
+		if !isTerminal(os.Stderr.Fd()) {
+			fatalf("live treemap requires stderr to be a terminal")
+		}
+		p = newLiveTreemap(os.Stderr, display{inodes: *scanFlags.inodes})
+	case !*hideProgress && isTerminal(os.Stderr.Fd()):
+		p = newProgress(os.Stderr, display{inodes: *scanFlags.inodes})
+	}
+
+	if p != nil {
+		log.SetOutput(p.writer(os.Stderr))
+	}
+
+	stopProfiling, err := profFlags.start()
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	outFlags.otherSize = scanFlags.otherSizeName()
+	outFlags.bothSizes = *scanFlags.bothSizes
+	outFlags.inodes = *scanFlags.inodes
+	outFlags.diskUsage = *scanFlags.diskUsage
+	outFlags.shared = *scanFlags.shared
+	outFlags.mtimes = *scanFlags.olderThan != "" || *scanFlags.newerThan != ""
+	outFlags.atimes = *scanFlags.notAccessed != ""
+	outFlags.modes = *scanFlags.perms != ""
+	outFlags.top = *scanFlags.topFiles > 0 || *scanFlags.topDirs > 0
+
+	reporter, out, err := outFlags.newReporter(p)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	defer out.Close()
+
+	opts := scanFlags.options(p)
+	opts.stream = *stream
+	opts.db = *db
+	// the flags rendering paths were checked when the reporter was created
+	opts.display, _ = outFlags.display()
+	if tableFormats[outFlags.format()] {
+		opts.tables = out
+	}
+
+	visualiser, err := newVisualiser(opts, reporter)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	// the first interrupt stops the scan and reports what was found so far, the next
+	// one terminates right away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err = visualiser.visualise(ctx, scanFlags.dirs()...)
+
+	stopProfiling()
+	if *profFlags.timings {
+		log.Printf("timings: %v", &visualiser.timings)
+	}
+
+	if errors.Is(err, errInterrupted) {
+		out.Close()
+		log.Printf("warning: %v", err)
+		os.Exit(exitInterrupted)
+	}
 	if err != nil {
-		log.Fatalf("%v", err)
+		fatalf("%v", err)
 	}
 
-	visualiser.visualise(*rootDir)
+	if *strict {
+		if failures := visualiser.failures.Load(); failures > 0 {
+			out.Close()
+			log.Printf("warning: %v entries could not be read, results are incomplete", failures)
+			os.Exit(exitFailed)
+		}
+		if visualiser.exceeded.Load() {
+			out.Close()
+			os.Exit(exitExceeded)
+		}
+	}
 
 	return
 }
 
-type visualiser struct {
-	sizeThreshold int64
-	ignoreRegexp  *regexp.Regexp
+// scanFlags control what is scanned and which entries are reported, they are shared by
+// all the commands scanning a directory.
+type scanFlags struct {
+	rootDirs        *stringsFlag
+	sizeThreshold   *string
+	maxSize         *string
+	fileThreshold   *string
+	dirThreshold    *string
+	percentOf       *string
+	reportTypes     *string
+	topFiles        *int
+	topDirs         *int
+	ignoreDirRegexp *stringsFlag
+	onlyRegexp      *stringsFlag
+	matchAgainst    *string
+	ignoreCase      *bool
+	filters         *[]filterPattern
+	excludeFrom     *stringsFlag
+	presets         *stringsFlag
+	exts            *string
+	notExts         *string
+	olderThan       *string
+	newerThan       *string
+	notAccessed     *string
+	perms           *string
+	owners          *string
+	groups          *string
+	maxDepth        *int
+	jobs            *int
+	cache           *bool
+	index           *string
+	rawReadDir      *bool
+	gentle          *bool
+	maxDirsPerSec   *int
+	dirTimeout      *time.Duration
+	noDedup         *bool
+	countLinks      *bool
+	countSpecial    *bool
+	shared          *bool
+	streams         *bool
+	streamsMin      *string
+	checkpoint      *string
+	checkpointEvery *time.Duration
+	resume          *bool
+	largeFirst      *bool
+	maxMemory       *string
+	symlinks        *symlinkMode
+	diskUsage       *bool
+	bothSizes       *bool
+	inodes          *bool
+	includeVirtual  *bool
+	includeSnaps    *bool
+	bundles         *bool
+	checkChanges    *bool
+	fsTotals        *bool
+	summaries       *string
+	compression     *bool
+	skipHidden      *bool
+	dirSizes        *bool
+	gitignore       *bool
+	noOwnIgnore     *bool
+	oneFileSystem   *bool
+	fsTypes         *string
+	excludedFsTypes *string
 }
 
-func newVisualiser(sizeThreshold string, ignoreRegexp string) (*visualiser, error) {
-	v := &visualiser{}
-
-	sizeThresholdParsed, err := humanize.ParseBigBytes(sizeThreshold)
-	if err != nil {
-		return nil, fmt.Errorf("invalid size threshold '%v': %v", sizeThreshold, err)
+func addScanFlags(fs *flag.FlagSet) *scanFlags {
+	f := &scanFlags{
+		rootDirs:        &stringsFlag{},
+		ignoreDirRegexp: &stringsFlag{},
+		onlyRegexp:      &stringsFlag{},
+		filters:         &[]filterPattern{},
+		excludeFrom:     &stringsFlag{},
+		presets:         &stringsFlag{},
+		symlinks:        new(symlinkMode),
+		sizeThreshold:   fs.String("s", sizeThresholdDefault, "print directories and files exceeding this threshold (example: 100MB), or this percentage of the scanned directory (example: 5%)"),
+		exts:            fs.String("ext", "", "comma-separated extensions of the only files to print (example: iso,img,qcow2), other files are still counted"),
+		notExts:         fs.String("not-ext", "", "comma-separated extensions of files not to print, they are still counted"),
+		olderThan:       fs.String("older-than", "", "print only files modified longer ago than this (example: 180d), d, w and y are accepted besides the units of Go durations, other files are still counted"),
+		newerThan:       fs.String("newer-than", "", "print only files modified more recently than this long ago (example: 7d), other files are still counted"),
+		notAccessed:     fs.String("not-accessed-since", "", "print only files not read for this long (example: 1y) going by their access times, which file systems mounted with noatime do not update, other files are still counted"),
+		perms:           fs.String("perm", "", "comma-separated permissions of the only files to print, any of them (example: o+w,u+s or 4000), other files are still counted"),
+		owners:          fs.String("owner", "", "comma-separated users whose files are the only ones to count, by names or IDs"),
+		groups:          fs.String("group", "", "comma-separated groups whose files are the only ones to count, by names or IDs"),
+		fileThreshold:   fs.String("file-threshold", "", "print files exceeding this threshold instead of -s (example: 50MB)"),
+		dirThreshold:    fs.String("dir-threshold", "", "print directories exceeding this threshold instead of -s (example: 1GB)"),
+		percentOf:       fs.String("percent-of", "root", "what percentage thresholds are taken of: root for the scanned directory or parent for the directory holding the entry"),
+		maxSize:         fs.String("max", "", "print no directories and files larger than this (example: 5GB), so that what lies next to known huge files shows up"),
+		reportTypes:     fs.String("type", "all", "which entries to print: f for files, d for directories or all"),
+		matchAgainst:    fs.String("match", matchPath, "what -i and -only regexps are matched against: path as joined to -d, relative to -d, absolute path or name"),
+		ignoreCase:      fs.Bool("ignore-case", false, "match -i, -only and -exclude whatever the case, the way case-insensitive file systems of Windows and macOS do"),
+		topFiles:        fs.Int("top-files", 0, "print this number of the largest files found, the largest first, instead of the entries exceeding -s"),
+		topDirs:         fs.Int("top-dirs", 0, "print this number of the largest directories no deeper than -max-depth, the largest first, instead of the entries exceeding -s"),
+		maxDepth:        fs.Int("max-depth", 0, "print entries no deeper than this number of levels below the scanned directory, 0 means no limit"),
+		jobs:            fs.Int("jobs", jobsDefault, "number of directories to read concurrently, 0 picks it by GOMAXPROCS and storage type"),
+		cache:           fs.Bool("cache", false, "reuse sizes of directories unchanged since the previous run, files modified in place are not noticed"),
+		index:           fs.String("index", "", "file to keep the index of scanned directories in, later scans only re-read changed directories"),
+		rawReadDir:      fs.Bool("raw-readdir", false, "on Linux read directories with getdents64 and open and stat entries relative to their directories, faster for metadata-heavy scans"),
+		gentle:          fs.Bool("gentle", false, "lower CPU and I/O priority of the scan and read one directory at a time unless -jobs is set"),
+		maxDirsPerSec:   fs.Int("max-dirs-per-sec", 0, "read no more than this number of directories per second, 0 means no limit"),
+		dirTimeout:      fs.Duration("dir-timeout", 0, "skip directories taking longer than this to read, e.g. on a hung network mount (example: 30s)"),
+		noDedup:         fs.Bool("no-dedup", false, "do not detect directories reached by several paths (bind mounts, overlapping -d), saves a stat per directory"),
+		diskUsage:       fs.Bool("disk-usage", false, "account files by the space they take on disk the way df does rather than by their apparent sizes"),
+		inodes:          fs.Bool("inodes", false, "size entries by the numbers of inodes they take instead of bytes, -s is a number of inodes then (default "+inodesThresholdDefault+")"),
+		includeVirtual:  fs.Bool("include-virtual", false, "scan mount points of virtual file systems like proc and sysfs, which are skipped otherwise"),
+		gitignore:       fs.Bool("respect-gitignore", false, "leave out what .gitignore and .ignore files found during the scan describe"),
+		noOwnIgnore:     fs.Bool("no-spacevisignore", false, "do not honor "+ownIgnoreFileName+" files, which list what to leave out of their directories the way .gitignore does"),
+		dirSizes:        fs.Bool("dir-sizes", false, "add the space taken by directories themselves to their totals the way du does, large for directories that once held many entries"),
+		skipHidden:      fs.Bool("skip-hidden", false, "ignore dot-files and dot-directories, and on Windows files with the hidden attribute"),
+		fsTotals:        fs.Bool("fs-totals", false, "print how much was found on each file system the way df does once the scan is over, after the report in the text, tree and bars formats and on stderr otherwise"),
+		summaries:       fs.String("summary", "", "comma-separated summaries of the scanned files to print once the scan is over: ext for the totals of their extensions, owner for the totals of their owners, age for the totals of files modified within the last week, month, 6 months, year and earlier, after the report in the text, tree and bars formats and on stderr otherwise"),
+		compression:     fs.Bool("estimate-compression", false, "once the scan is over, gzip a few MB of each of the largest reported files to estimate how much compressing them would save, after the report in the text, tree and bars formats and on stderr otherwise"),
+		checkChanges:    fs.Bool("check-changes", false, "stat directories again once they are scanned to flag the ones changed meanwhile as possibly stale"),
+		bundles:         fs.Bool("bundles", false, "report macOS bundles like .app and .photoslibrary as single entries without listing their contents"),
+		includeSnaps:    fs.Bool("include-snapshots", false, "scan ZFS snapshots in .zfs/snapshot and btrfs snapshot subvolumes, which are skipped otherwise"),
+		fsTypes:         fs.String("fs-type", "", "comma-separated types of the only file systems to scan (example: ext4,xfs), fuse stands for all of fuse.*"),
+		excludedFsTypes: fs.String("exclude-fs-type", "", "comma-separated types of file systems to skip (example: nfs,fuse.sshfs)"),
+		oneFileSystem:   fs.Bool("x", false, "skip directories on other file systems than the scanned one"),
+		bothSizes:       fs.Bool("both-sizes", false, "print both the apparent size and the disk usage in the text format"),
+		countLinks:      fs.Bool("count-links", false, "count sizes of files with several hard links as many times as they are found instead of once"),
+		countSpecial:    fs.Bool("count-special", false, "count sockets, named pipes and devices as empty files instead of skipping them"),
+		streams:         fs.Bool("streams", false, "on Windows add alternate data streams of files to their sizes"),
+		streamsMin:      fs.String("streams-threshold", streamsThresholdDefault, "with -streams print files keeping more than this in alternate data streams whatever their sizes"),
+		shared:          fs.Bool("shared", false, "find out how much of the disk usage is shared with other files by reflinks, deduplication or snapshots (btrfs, XFS), opens every file"),
+		checkpoint:      fs.String("checkpoint", "", "periodically save the state of the scan to this file, so that an interrupted scan can be continued with -resume"),
+		checkpointEvery: fs.Duration("checkpoint-interval", checkpointIntervalDefault, "how often to save the checkpoint"),
+		resume:          fs.Bool("resume", false, "continue the scan saved to -checkpoint, already read directories are not read again"),
+		largeFirst:      fs.Bool("large-first", false, "scan probably large directories first, guessing by the cache or index and by sizes of directories themselves"),
+		maxMemory:       fs.String("max-memory", "", "keep memory usage under this size (example: 512MB) by giving up reading ahead, the cache and the database as it is approached"),
 	}
 
-	v.sizeThreshold = sizeThresholdParsed.Int64()
+	fs.StringVar(f.sizeThreshold, "min", sizeThresholdDefault, "same as -s")
+	fs.Var(symlinkFlag{f.symlinks, symlinksNever}, "P", "do not follow symbolic links")
+	fs.Var(symlinkFlag{f.symlinks, symlinksRoots}, "H", "follow symbolic links given with -d only")
+	fs.Var(symlinkFlag{f.symlinks, symlinksAlways}, "L", "follow all symbolic links, counting what they point to")
+	fs.Var(f.ignoreDirRegexp, "i", "regexp of directories to ignore, may be repeated to ignore directories matching any of them")
+	fs.Var(f.onlyRegexp, "only", "regexp of the only paths to count (example: '/target$'), everything else is skipped though directories are looked into for matches, may be repeated")
+	fs.Var(filterFlag{f.filters, false}, "exclude", "shell-style pattern of files and directories to ignore (example: '*.iso' or '**/cache/**'), matched against names unless it has a slash, may be repeated")
+	fs.Var(filterFlag{f.filters, true}, "include", "shell-style pattern of files and directories to scan even if an -exclude given before matches them (example: 'logs/audit/**'), the last matching pattern decides, may be repeated")
+	fs.Var(f.excludeFrom, "exclude-from", "file listing -exclude patterns one per line, may be repeated")
+	fs.Var(f.presets, "preset", "named set of -exclude patterns: dev (node_modules, .git, target, __pycache__, .venv) or linux-system (/proc, /sys, /dev, /run), may be repeated")
+	fs.Var(f.rootDirs, "d", "directory to search, may be repeated to scan several directories concurrently (default \""+rootDirDefault+"\")")
+
+	return f
+}
 
-	if ignoreRegexp != "" {
-		ignoreRegexpParsed, err := regexp.Compile(ignoreRegexp)
-		if err != nil {
-			return nil, fmt.Errorf("could not compile regexp '%s': %v", ignoreRegexp, err)
-		}
-		v.ignoreRegexp = ignoreRegexpParsed
+// dirs returns the directories to scan.
+func (f *scanFlags) dirs() []string {
+	if len(*f.rootDirs) == 0 {
+		return []string{rootDirDefault}
 	}
 
-	return v, nil
+	return *f.rootDirs
 }
 
-func (v *visualiser) shouldSkipDir(dir string) bool {
-	return v.ignoreRegexp != nil && v.ignoreRegexp.MatchString(dir)
+// stringsFlag collects the values of a flag that may be repeated.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
 }
 
-func (v *visualiser) visualise(dir string) {
-	dirSize, _, err := v.getDirSize(dir)
-	if err != nil {
-		log.Printf("error: could not visualise directory %v: %v", dir, err)
-		return
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func (f *scanFlags) options(p scanProgress) visualiserOptions {
+	sizeThreshold := *f.sizeThreshold
+	if *f.inodes && sizeThreshold == sizeThresholdDefault {
+		sizeThreshold = inodesThresholdDefault
 	}
 
-	if dirSize > v.sizeThreshold {
-		fmt.Printf("%v: %v\n", dir, humanize.BigBytes(big.NewInt(dirSize)))
-		fmt.Println()
+	return visualiserOptions{
+		sizeThreshold:    sizeThreshold,
+		maxSize:          *f.maxSize,
+		fileThreshold:    *f.fileThreshold,
+		dirThreshold:     *f.dirThreshold,
+		percentOf:        *f.percentOf,
+		reportTypes:      *f.reportTypes,
+		topFiles:         *f.topFiles,
+		topDirs:          *f.topDirs,
+		ignoreRegexps:    *f.ignoreDirRegexp,
+		onlyRegexps:      *f.onlyRegexp,
+		matchAgainst:     *f.matchAgainst,
+		ignoreCase:       *f.ignoreCase,
+		filters:          *f.filters,
+		excludeFrom:      *f.excludeFrom,
+		presets:          *f.presets,
+		exts:             *f.exts,
+		notExts:          *f.notExts,
+		olderThan:        *f.olderThan,
+		newerThan:        *f.newerThan,
+		notAccessedSince: *f.notAccessed,
+		perms:            *f.perms,
+		owners:           *f.owners,
+		groups:           *f.groups,
+		maxDepth:         *f.maxDepth,
+		jobs:             *f.jobs,
+		cache:            *f.cache,
+		index:            *f.index,
+		rawReadDir:       *f.rawReadDir,
+		gentle:           *f.gentle,
+		maxDirsPerSec:    *f.maxDirsPerSec,
+		dirTimeout:       *f.dirTimeout,
+		noDedup:          *f.noDedup,
+		countLinks:       *f.countLinks,
+		countSpecial:     *f.countSpecial,
+		shared:           *f.shared,
+		streams:          *f.streams,
+		streamsThreshold: *f.streamsMin,
+		diskUsage:        *f.diskUsage,
+		inodes:           *f.inodes,
+		includeVirtual:   *f.includeVirtual,
+		includeSnapshots: *f.includeSnaps,
+		bundles:          *f.bundles,
+		checkChanges:     *f.checkChanges,
+		fsTotals:         *f.fsTotals,
+		summaries:        *f.summaries,
+		estimateSavings:  *f.compression,
+		skipHidden:       *f.skipHidden,
+		dirSizes:         *f.dirSizes,
+		respectGitignore: *f.gitignore,
+		noOwnIgnoreFiles: *f.noOwnIgnore,
+		oneFileSystem:    *f.oneFileSystem,
+		fsTypes:          *f.fsTypes,
+		excludedFsTypes:  *f.excludedFsTypes,
+
+		checkpoint:         *f.checkpoint,
+		checkpointInterval: *f.checkpointEvery,
+		resume:             *f.resume,
+		largeFirst:         *f.largeFirst,
+		maxMemory:          *f.maxMemory,
+		symlinks:           *f.symlinks,
+		progress:           p,
 	}
 }
 
-// getDirSize calculates size for the given directory recursively. It prints size of entries
-// exceeding the sizeThreshold.
-func (v *visualiser) getDirSize(dir string) (int64, int, error) {
-	dirEntries, err := os.ReadDir(dir)
-	if err != nil {
-		log.Printf("error: could not read contents of directory %v: %v", dir, err)
-		log.Printf("warning: will skip directory %v in calculations", dir)
+// otherSizeName names the size of entries other than the one they are accounted by, it
+// is empty if there is none.
+func (f *scanFlags) otherSizeName() string {
+	if *f.inodes {
+		return ""
+	}
 
-		return 0, 0, nil
+	if *f.diskUsage {
+		return "apparent"
 	}
 
-	dirSize := int64(0)
-	filesPrintedInThisDir := 0
-	shouldPrintAClosingNewLine := false
+	return "on disk"
+}
 
-	for _, entry := range dirEntries {
-		fullPath := filepath.Join(dir, entry.Name())
-		entrySize := int64(0)
+// outputFlags control how the results are rendered, they are shared by all the commands
+// producing a report.
+type outputFlags struct {
+	formatName *string
+	output     *string
+	columns    *string
+	template   *string
+	du         *bool
+	tree       *bool
+	tui        *bool
+	bars       *bool
+	color      *string
+	bands      *string
+	noPager    *bool
+	counts     *bool
+	rawPaths   *bool
+	normalize  *string
+	sortBy     *string
+	reverse    *bool
+
+	// otherSize, bothSizes, inodes, diskUsage, shared, mtimes, atimes and modes are set by
+	// the commands scanning a directory, entries read from a file have only one size.
+	otherSize string
+	bothSizes bool
+	inodes    bool
+	diskUsage bool
+	shared    bool
+	mtimes    bool
+	atimes    bool
+	modes     bool
+	// top is set if the largest entries are reported in place of the ones exceeding the
+	// thresholds, they are not reported directory by directory then.
+	top bool
+}
 
-		switch {
-		case entry.Type().IsRegular():
-			info, err := entry.Info()
-			entrySize = info.Size()
+func addOutputFlags(fs *flag.FlagSet) *outputFlags {
+	return &outputFlags{
+		formatName: fs.String("format", formatDefault, "output format, one of: "+strings.Join(formats, ", ")),
+		output:     fs.String("output", outputDefault, "file to write results to instead of stdout"),
+		columns:    fs.String("columns", columnsDefault, "comma-separated list of columns for -format tsv ("+strings.Join(tsvColumnNames, ", ")+")"),
+		template:   fs.String("template", "", "text/template executed for each entry, a template named 'footer' is executed for the root once the scan is over, same as -format template"),
+		du:         fs.Bool("du", false, "print results the way 'du -k' does, same as -format du"),
+		tree:       fs.Bool("tree", false, "print results nested under their parent directories, same as -format tree"),
+		tui:        fs.Bool("tui", false, "browse results interactively once the scan is over, same as -format tui"),
+		bars:       fs.Bool("bars", false, "print results with bars proportional to their sizes, same as -format bars"),
+		color:      fs.String("color", colorAuto, "colorize entries by size: auto, always or never"),
+		bands:      fs.String("color-bands", colorBandsDefault, "comma-separated SIZE=COLOR pairs, entries exceeding SIZE are painted with COLOR"),
+		noPager:    fs.Bool("no-pager", false, "do not pipe output that does not fit the terminal into $PAGER"),
+		counts:     fs.Bool("counts", false, "print the numbers of files and directories within directories in the text format"),
+		rawPaths:   fs.Bool("raw-paths", false, "print paths as they are instead of escaping control characters and invalid UTF-8 in the line-oriented formats"),
+		sortBy:     fs.String("sort", "", "print entries once the scan is over sorted by size, mtime (both the largest first) or name, in the formats printing one entry per line"),
+		reverse:    fs.Bool("reverse", false, "reverse the order of -sort"),
+		normalize:  fs.String("normalize", normalizeNone, "bring paths in the line-oriented formats to the Unicode normalization form, nfc or nfd (macOS keeps names in nfd)"),
+	}
+}
 
-			if err != nil {
-				log.Printf("error: could not get info for file %v: %v", fullPath, err)
-				log.Printf("warning: file %v will not be included in calculations", fullPath)
+// format returns the output format taking the shortcut flags into account.
+func (f *outputFlags) format() string {
+	switch {
+	case *f.du:
+		return formatDU
+	case *f.tree:
+		return formatTree
+	case *f.template != "":
+		return formatTemplate
+	case *f.tui:
+		return formatTUI
+	case *f.bars:
+		return formatBars
+	}
 
-				continue
-			}
+	return *f.formatName
+}
 
-		case entry.Type().IsDir():
-			if v.shouldSkipDir(fullPath) {
-				log.Printf(
-					"warning: ignoring directory '%v' due to matched ignore-regexp", fullPath,
-				)
+// display renders paths and sizes the way the flags ask for.
+func (f *outputFlags) display() (display, error) {
+	render, err := pathRenderer(*f.rawPaths, *f.normalize)
+	if err != nil {
+		return display{}, err
+	}
 
-				continue
-			}
+	return display{paths: render, raw: *f.rawPaths, inodes: f.inodes}, nil
+}
 
-			var filesPrintedInThisEntry int
+// newReporter creates the reporter requested by the flags together with the destination
+// it writes to, the caller is responsible for closing the destination once the report is
+// finished. p may be nil if no progress is shown.
+func (f *outputFlags) newReporter(p scanProgress) (reporter, io.WriteCloser, error) {
+	format := f.format()
 
-			entrySize, filesPrintedInThisEntry, err = v.getDirSize(fullPath)
-			if err != nil {
-				log.Printf("error: could not read contents of directory %v: %v", dir, err)
-				log.Printf("warning: will skip directory %v in calculations", dir)
+	d, err := f.display()
+	if err != nil {
+		return nil, nil, err
+	}
 
-				continue
-			}
+	out := os.Stdout
+	if *f.output != "" {
+		var err error
 
-			if filesPrintedInThisEntry > 0 {
-				shouldPrintAClosingNewLine = true
-			}
+		out, err = os.Create(*f.output)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not create output file: %v", err)
 		}
+	}
+
+	opts := reportOptions{
+		columns:   *f.columns,
+		template:  *f.template,
+		width:     tuiDefaultWidth,
+		otherSize: f.otherSize,
+		bothSizes: f.bothSizes,
+		counts:    *f.counts,
+		diskUsage: f.diskUsage,
+		shared:    f.shared,
+		mtimes:    f.mtimes,
+		atimes:    f.atimes,
+		modes:     f.modes,
+		flat:      *f.sortBy != "" || f.top,
+	}
+	opts.display = d
+
+	if width, _, err := terminalSize(out.Fd()); err == nil && width > 0 {
+		opts.width = width
+	}
 
-		if entrySize > v.sizeThreshold {
-			if entry.Type().IsRegular() && filesPrintedInThisDir == 0 {
-				// create an empty line before a group of files in one directory
-				fmt.Println()
-			}
+	colorize, err := shouldColorize(*f.color, out)
+	if err == nil && colorize {
+		opts.colors, err = newColorizer(*f.bands)
+	}
+	if err != nil {
+		out.Close()
+		return nil, nil, err
+	}
 
-			fmt.Printf("%v: %v\n", fullPath, humanize.BigBytes(big.NewInt(entrySize)))
+	var dst io.WriteCloser = out
+	if *f.output == "" && !*f.noPager && !binaryFormats[format] && isTerminal(out.Fd()) {
+		dst = newPager(out)
+	}
 
-			if shouldPrintAClosingNewLine {
-				// create an empty line after a group of files in one directory
-				fmt.Println()
-			}
+	var w io.Writer = dst
+	if p != nil {
+		w = p.writer(dst)
+	}
 
-			if entry.Type().IsRegular() {
-				filesPrintedInThisDir++
-			}
+	reporter, err := newReporter(format, w, opts)
+	if err == nil && *f.sortBy != "" {
+		if !sortableFormats[format] {
+			err = fmt.Errorf("format '%v' does not print entries one by one, they cannot be sorted", format)
+		} else {
+			reporter, err = newSortingReporter(reporter, *f.sortBy, *f.reverse)
 		}
-
-		dirSize += entrySize
+	}
+	if err != nil {
+		out.Close()
+		return nil, nil, err
 	}
 
-	return dirSize, filesPrintedInThisDir, nil
+	return reporter, dst, nil
 }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"log"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+const (
+	memoryCheckInterval = 100 * time.Millisecond
+
+	// the scan starts saving memory once the heap grows past this share of the limit
+	memoryHighWatermark = 0.8
+)
+
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// watchMemory raises v.memoryLow once the heap approaches maxMemory until the returned
+// function is called. It also makes the garbage collector keep the heap under the limit.
+func (v *visualiser) watchMemory() func() {
+	if v.maxMemory <= 0 {
+		return func() {}
+	}
+
+	debug.SetMemoryLimit(v.maxMemory)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		sample := []metrics.Sample{{Name: heapObjectsMetric}}
+
+		ticker := time.NewTicker(memoryCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				metrics.Read(sample)
+				if sample[0].Value.Kind() != metrics.KindUint64 {
+					return
+				}
+
+				if float64(sample[0].Value.Uint64()) > memoryHighWatermark*float64(v.maxMemory) {
+					v.memoryLow.Store(true)
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// saveMemory gives up everything the scan keeps besides what the report needs: reading
+// directories ahead, the cache and the tree kept for the database. It is called by the
+// scanning goroutines once memory runs low.
+func (v *visualiser) saveMemory() {
+	v.memorySaved.Do(func() {
+		log.Printf("warning: memory usage approaches the limit, directories are not read ahead anymore")
+
+		if v.cache != nil {
+			v.cache.drop()
+			log.Printf("warning: memory usage approaches the limit, the cache is dropped")
+		}
+
+		if v.db != "" && !v.reportNeedsTree {
+			v.treeDropped.Store(true)
+			log.Printf("warning: memory usage approaches the limit, the database will not be saved")
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// fileSystem describes the file system mounted at a directory.
+type fileSystem struct {
+	name   string
+	device string
+
+	// bindOf is the directory the file system is bind mounted from, it is empty unless
+	// the mount point is an alias of a directory mounted elsewhere.
+	bindOf string
+
+	// accessTimes is "noatime" if access times of files are never updated, "relatime"
+	// if only once a day, empty if on every read or unknown.
+	accessTimes string
+}
+
+// mountPointAt returns the file system mounted at the directory, nil if it is not a mount
+// point. Mount points are looked up by their absolute paths in the list of mounted file
+// systems read at the start of the scan, so that directories need not be stat-ed.
+func (v *visualiser) mountPointAt(path string) *fileSystem {
+	if len(v.mounts) == 0 {
+		return nil
+	}
+
+	return v.mounts[resolvePath(v.cwd, path)]
+}
+
+// skipMount tells whether the file system mounted at the directory is not to be scanned:
+// virtual file systems like proc describe the system rather than hold data, their sizes
+// are meaningless.
+func (v *visualiser) skipMount(path string, fs *fileSystem) bool {
+	reason := v.mountSkipReason(path, fs)
+	if reason == "" {
+		return false
+	}
+
+	log.Printf("warning: skipping directory %v, %v", path, reason)
+
+	return true
+}
+
+// mountSkipReason tells why the file system mounted at the directory is not to be
+// scanned, it is empty if the file system is scanned.
+func (v *visualiser) mountSkipReason(path string, fs *fileSystem) string {
+	switch {
+	case v.oneFileSystem:
+		return fmt.Sprintf("it is a mount point of %v", fs)
+
+	case v.skipVirtual && isVirtualFileSystem(path, fs):
+		return fmt.Sprintf("it is a mount point of virtual file system %v", fs.name)
+
+	case fs.bindOf != "" && v.isScannedPath(fs.bindOf):
+		return fmt.Sprintf("it is a bind mount of %v which is scanned as well", fs.bindOf)
+
+	case !v.isScannedFileSystemType(fs.name):
+		return fmt.Sprintf("it is a mount point of %v which is of a type not to be scanned", fs)
+	}
+
+	return ""
+}
+
+// parseFileSystemTypes parses a comma-separated list of types of file systems.
+func parseFileSystemTypes(list string) map[string]bool {
+	if list == "" {
+		return nil
+	}
+
+	types := map[string]bool{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			types[name] = true
+		}
+	}
+
+	return types
+}
+
+// isScannedFileSystemType tells whether file systems of the type are to be scanned given
+// -fs-type and -exclude-fs-type. A type is given either exactly or by the part before the
+// dot, like fuse for fuse.sshfs.
+func (v *visualiser) isScannedFileSystemType(name string) bool {
+	kind, _, _ := strings.Cut(name, ".")
+	listed := func(types map[string]bool) bool { return types[name] || types[kind] }
+
+	return (v.fsTypes == nil || listed(v.fsTypes)) && !listed(v.excludedFsTypes)
+}
+
+// isScannedPath tells whether the absolute path lies within one of the scanned roots.
+func (v *visualiser) isScannedPath(path string) bool {
+	for _, root := range v.roots {
+		if isWithin(path, root) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (fs *fileSystem) String() string {
+	if fs.bindOf != "" {
+		return fs.name + " on " + fs.device + ", bind mount of " + fs.bindOf
+	}
+
+	return fs.name + " on " + fs.device
+}
+
+// mountString describes the file system mounted at the entry, it is empty if the entry is
+// not a mount point.
+func mountString(e *entry) string {
+	if e.mount == nil {
+		return ""
+	}
+
+	return e.mount.String()
+}
@@ -0,0 +1,80 @@
+//go:build darwin || freebsd
+
+package main
+
+import "syscall"
+
+const (
+	mntNowait  = 2
+	mntNoatime = 0x10000000
+)
+
+// virtualFileSystemNames hold no data of their own.
+var virtualFileSystemNames = map[string]bool{
+	"devfs":     true,
+	"procfs":    true,
+	"linprocfs": true,
+	"linsysfs":  true,
+	"fdescfs":   true,
+	"mqueuefs":  true,
+}
+
+func isVirtualFileSystem(path string, fs *fileSystem) bool {
+	if virtualFileSystemNames[fs.name] {
+		return true
+	}
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return false
+	}
+
+	return virtualFileSystemNames[cString(st.Fstypename[:])]
+}
+
+// loadMounts lists the file systems mounted in the system with getfsstat.
+func loadMounts() map[string]*fileSystem {
+	mounts := map[string]*fileSystem{}
+
+	n, err := syscall.Getfsstat(nil, mntNowait)
+	if err != nil {
+		return mounts
+	}
+
+	buf := make([]syscall.Statfs_t, n)
+	if n, err = syscall.Getfsstat(buf, mntNowait); err != nil {
+		return mounts
+	}
+
+	for _, st := range buf[:n] {
+		fs := &fileSystem{
+			name:   cString(st.Fstypename[:]),
+			device: cString(st.Mntfromname[:]),
+		}
+
+		if uint64(st.Flags)&mntNoatime != 0 {
+			fs.accessTimes = "noatime"
+		}
+
+		// nullfs mounts a directory from elsewhere, the source is the directory itself
+		if fs.name == "nullfs" {
+			fs.bindOf = fs.device
+		}
+
+		mounts[cString(st.Mntonname[:])] = fs
+	}
+
+	return mounts
+}
+
+func cString(b []int8) string {
+	s := make([]byte, 0, len(b))
+	for _, c := range b {
+		if c == 0 {
+			break
+		}
+		s = append(s, byte(c))
+	}
+
+	return string(s)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// virtualFileSystems are the statfs magic numbers of the file systems that describe the
+// system rather than hold data.
+var virtualFileSystems = map[uint32]bool{
+	0x9fa0:     true, // proc
+	0x62656572: true, // sysfs
+	0x27e0eb:   true, // cgroup
+	0x63677270: true, // cgroup2
+	0x64626720: true, // debugfs
+	0x74726163: true, // tracefs
+	0x73636673: true, // securityfs
+	0x6165676c: true, // pstore
+	0xcafe4a11: true, // bpf
+	0x62656570: true, // configfs
+	0x1cd1:     true, // devpts
+	0x19800202: true, // mqueue
+	0x958458f6: true, // hugetlbfs
+	0x65735543: true, // fusectl
+	0x42494e4d: true, // binfmt_misc
+	0x6e736673: true, // nsfs
+	0xf97cff8c: true, // selinuxfs
+	0xde5e81e4: true, // efivarfs
+}
+
+// virtualFileSystemNames are the types of the virtual file systems as mountinfo names
+// them, devtmpfs shares its magic number with tmpfs and is only told by its name.
+var virtualFileSystemNames = map[string]bool{
+	"proc":        true,
+	"sysfs":       true,
+	"devtmpfs":    true,
+	"cgroup":      true,
+	"cgroup2":     true,
+	"debugfs":     true,
+	"tracefs":     true,
+	"securityfs":  true,
+	"pstore":      true,
+	"bpf":         true,
+	"configfs":    true,
+	"devpts":      true,
+	"mqueue":      true,
+	"hugetlbfs":   true,
+	"fusectl":     true,
+	"binfmt_misc": true,
+	"nsfs":        true,
+	"selinuxfs":   true,
+	"efivarfs":    true,
+	"autofs":      true,
+	"rpc_pipefs":  true,
+}
+
+// isVirtualFileSystem tells by the type of the file system mounted at the path or by its
+// statfs magic number whether it is a virtual one.
+func isVirtualFileSystem(path string, fs *fileSystem) bool {
+	if virtualFileSystemNames[fs.name] {
+		return true
+	}
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return false
+	}
+
+	return virtualFileSystems[uint32(st.Type)]
+}
+
+// mountRecord is a line of /proc/self/mountinfo.
+type mountRecord struct {
+	dev        string
+	root       string
+	mountPoint string
+	fs         *fileSystem
+}
+
+// loadMounts reads the file systems mounted in the system from /proc/self/mountinfo.
+func loadMounts() map[string]*fileSystem {
+	mounts := map[string]*fileSystem{}
+
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return mounts
+	}
+	defer f.Close()
+
+	var records []mountRecord
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// id, parent id, major:minor, root, mount point, options, optional fields
+		// terminated by "-", file system type, source, super options
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if field == "-" && i > 4 && i+2 < len(fields) {
+				rec := mountRecord{
+					dev:        fields[2],
+					root:       unescapeMountinfo(fields[3]),
+					mountPoint: unescapeMountinfo(fields[4]),
+					fs: &fileSystem{
+						name:        fields[i+1],
+						device:      unescapeMountinfo(fields[i+2]),
+						accessTimes: accessTimesOf(fields[5]),
+					},
+				}
+
+				records = append(records, rec)
+				mounts[rec.mountPoint] = rec.fs
+
+				break
+			}
+		}
+	}
+
+	findBindMounts(records)
+
+	return mounts
+}
+
+// findBindMounts tells the aliases among the mounts of each file system: the mount of the
+// outermost directory of a file system is taken for the original, the mounts of the
+// directories within it are its aliases.
+func findBindMounts(records []mountRecord) {
+	primary := map[string]mountRecord{}
+	for _, rec := range records {
+		if p, ok := primary[rec.dev]; !ok || len(rec.root) < len(p.root) {
+			primary[rec.dev] = rec
+		}
+	}
+
+	for _, rec := range records {
+		p := primary[rec.dev]
+		if rec.mountPoint == p.mountPoint || !isWithin(rec.root, p.root) {
+			continue
+		}
+
+		rel, err := filepath.Rel(p.root, rec.root)
+		if err != nil {
+			continue
+		}
+
+		rec.fs.bindOf = filepath.Join(p.mountPoint, rel)
+	}
+}
+
+// accessTimesOf tells by the options of a mount how access times are updated on it.
+func accessTimesOf(options string) string {
+	for _, opt := range strings.Split(options, ",") {
+		if opt == "noatime" || opt == "relatime" {
+			return opt
+		}
+	}
+
+	return ""
+}
+
+// unescapeMountinfo decodes the octal escapes mountinfo uses for spaces, tabs, newlines
+// and backslashes.
+func unescapeMountinfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+
+				continue
+			}
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
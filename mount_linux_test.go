@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestUnescapeMountinfo(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{`/mnt/data`, "/mnt/data"},
+		{`/mnt/my\040disk`, "/mnt/my disk"},
+		{`/mnt/a\011b`, "/mnt/a\tb"},
+		{`/mnt/a\012b`, "/mnt/a\nb"},
+		{`/mnt/a\134b`, `/mnt/a\b`},
+		{`\040\040`, "  "},
+		{`/mnt/a\04`, `/mnt/a\04`},
+		{`/mnt/a\99x`, `/mnt/a\99x`},
+		{`/mnt/a\`, `/mnt/a\`},
+	}
+
+	for _, tt := range tests {
+		if got := unescapeMountinfo(tt.s); got != tt.want {
+			t.Errorf("unescapeMountinfo(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestFindBindMounts(t *testing.T) {
+	records := []mountRecord{
+		{dev: "8:1", root: "/", mountPoint: "/", fs: &fileSystem{name: "ext4"}},
+		{dev: "8:1", root: "/var/lib/docker", mountPoint: "/srv/docker", fs: &fileSystem{name: "ext4"}},
+		{dev: "8:2", root: "/", mountPoint: "/home", fs: &fileSystem{name: "xfs"}},
+		{dev: "8:2", root: "/user/data", mountPoint: "/data", fs: &fileSystem{name: "xfs"}},
+		{dev: "0:5", root: "/", mountPoint: "/proc", fs: &fileSystem{name: "proc"}},
+		// a file system mounted only by a subdirectory has no original to be an alias of
+		{dev: "0:40", root: "/volumes/a", mountPoint: "/mnt/a", fs: &fileSystem{name: "btrfs"}},
+	}
+
+	findBindMounts(records)
+
+	want := map[string]string{
+		"/":           "",
+		"/srv/docker": "/var/lib/docker",
+		"/home":       "",
+		"/data":       "/home/user/data",
+		"/proc":       "",
+		"/mnt/a":      "",
+	}
+	for _, rec := range records {
+		if got := rec.fs.bindOf; got != want[rec.mountPoint] {
+			t.Errorf("bindOf of %v = %q, want %q", rec.mountPoint, got, want[rec.mountPoint])
+		}
+	}
+}
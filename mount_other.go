@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd
+
+package main
+
+func isVirtualFileSystem(path string, fs *fileSystem) bool {
+	return false
+}
+
+func loadMounts() map[string]*fileSystem {
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	normalizeNone = ""
+	normalizeNFC  = "nfc"
+	normalizeNFD  = "nfd"
+)
+
+// normalizer returns the function bringing names to the Unicode normalization form: macOS
+// keeps names decomposed (NFD) while most other systems keep them composed (NFC), so the
+// same name may be spelled both ways. Names that are not valid UTF-8 are left as they
+// are.
+func normalizer(form string) (func(string) string, error) {
+	switch strings.ToLower(form) {
+	case normalizeNone:
+		return nil, nil
+	case normalizeNFC:
+		return normalizeValid(norm.NFC), nil
+	case normalizeNFD:
+		return normalizeValid(norm.NFD), nil
+	default:
+		return nil, fmt.Errorf("unknown normalization form '%v', expected %v or %v", form, normalizeNFC, normalizeNFD)
+	}
+}
+
+func normalizeValid(f norm.Form) func(string) string {
+	return func(s string) string {
+		if !utf8.ValidString(s) {
+			return s
+		}
+
+		return f.String(s)
+	}
+}
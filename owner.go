@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// parseOwners resolves a comma-separated list of user or group names or numeric IDs.
+func parseOwners(list string, lookup func(name string) (uint32, error)) (map[uint32]bool, error) {
+	if list == "" {
+		return nil, nil
+	}
+
+	ids := map[uint32]bool{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+
+		id, err := lookup(name)
+		if err != nil {
+			return nil, fmt.Errorf("could not look up '%v': %v", name, err)
+		}
+
+		ids[id] = true
+	}
+
+	return ids, nil
+}
+
+// isOwned tells whether the file is to be counted given -owner and -group, files whose
+// owners are unknown are not.
+func (v *visualiser) isOwned(info os.FileInfo) bool {
+	if v.owners == nil && v.groups == nil {
+		return true
+	}
+	if info == nil {
+		return false
+	}
+
+	uid, gid, ok := fileOwner(info)
+
+	return ok && (v.owners == nil || v.owners[uid]) && (v.groups == nil || v.groups[gid])
+}
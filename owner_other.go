@@ -0,0 +1,27 @@
+//go:build !linux && !darwin && !freebsd
+
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+)
+
+var errOwnersUnsupported = errors.New("owners of files are not known on this platform")
+
+func fileOwner(info os.FileInfo) (uid, gid uint32, ok bool) {
+	return 0, 0, false
+}
+
+func lookupUser(name string) (uint32, error) {
+	return 0, errOwnersUnsupported
+}
+
+func userName(uid uint32) string {
+	return strconv.FormatUint(uint64(uid), 10)
+}
+
+func lookupGroup(name string) (uint32, error) {
+	return 0, errOwnersUnsupported
+}
@@ -0,0 +1,69 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// fileOwner returns the user and the group owning the file.
+func fileOwner(info os.FileInfo) (uid, gid uint32, ok bool) {
+	if c, ok := info.(cachedEntry); ok {
+		return c.UID, c.GID, c.HasOwner
+	}
+
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return st.Uid, st.Gid, true
+}
+
+// lookupUser finds out the ID of the user, numeric IDs are taken as they are.
+func lookupUser(name string) (uint32, error) {
+	if id, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(id), nil
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.ParseUint(u.Uid, 10, 32)
+
+	return uint32(id), err
+}
+
+// userName finds out the name of the user, the numeric ID is returned if the user is
+// unknown.
+func userName(uid uint32) string {
+	id := strconv.FormatUint(uint64(uid), 10)
+
+	u, err := user.LookupId(id)
+	if err != nil {
+		return id
+	}
+
+	return u.Username
+}
+
+// lookupGroup finds out the ID of the group, numeric IDs are taken as they are.
+func lookupGroup(name string) (uint32, error) {
+	if id, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(id), nil
+	}
+
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.ParseUint(g.Gid, 10, 32)
+
+	return uint32(id), err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+const (
+	pagerDefault = "less"
+	// lessDefault makes less quit if the output fits one screen, keep colors and not
+	// clear the screen on exit, the same as git does.
+	lessDefault = "FRX"
+)
+
+// binaryFormats are never sent to a pager.
+var binaryFormats = map[string]bool{
+	formatParquet: true,
+	formatXLSX:    true,
+	formatMsgpack: true,
+	formatTUI:     true,
+}
+
+// pager shows the output via $PAGER as it is written, less quits right away if the
+// output fits the terminal. Once the pager is quit, the rest of the output is dropped.
+type pager struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// newPager starts the pager writing to out, the output is written to out as is if there
+// is no pager to start.
+func newPager(out *os.File) io.WriteCloser {
+	command := os.Getenv("PAGER")
+	if command == "" {
+		if _, err := exec.LookPath(pagerDefault); err != nil {
+			return out
+		}
+
+		command = pagerDefault
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS="+lessDefault)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return out
+	}
+
+	if err := cmd.Start(); err != nil {
+		// the pager could not be started, fall back to printing everything
+		stdin.Close()
+		return out
+	}
+
+	return &pager{
+		cmd:   cmd,
+		stdin: stdin,
+	}
+}
+
+func (p *pager) Write(b []byte) (int, error) {
+	n, err := p.stdin.Write(b)
+	if errors.Is(err, syscall.EPIPE) {
+		// the pager is quit, nobody is going to read the rest
+		return len(b), nil
+	}
+
+	return n, err
+}
+
+func (p *pager) Close() error {
+	if err := p.stdin.Close(); err != nil && !errors.Is(err, syscall.EPIPE) {
+		return err
+	}
+
+	if err := p.cmd.Wait(); err != nil {
+		return fmt.Errorf("pager failed: %v", err)
+	}
+
+	return nil
+}
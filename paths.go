@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pathRenderer returns the function rendering paths: they are escaped unless raw is set
+// and normalized to the form if one is given.
+func pathRenderer(raw bool, form string) (func(string) string, error) {
+	normalize, err := normalizer(form)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case raw && normalize == nil:
+		return func(p string) string { return p }, nil
+	case raw:
+		return normalize, nil
+	case normalize == nil:
+		return escapePath, nil
+	}
+
+	return func(p string) string { return escapePath(normalize(p)) }, nil
+}
+
+// escapePath replaces control characters, line and paragraph separators, bidirectional
+// overrides and bytes that are not valid UTF-8 with Go escape sequences. Backslashes are
+// doubled, so that a name spelling an escape sequence is told from the escaped one, unless
+// they separate paths as on Windows.
+func escapePath(p string) string {
+	if !needsEscaping(p) {
+		return p
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(p); {
+		r, size := utf8.DecodeRuneInString(p[i:])
+
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&b, `\x%02x`, p[i])
+		case shouldEscape(r):
+			// the quoted rune is the escape sequence in single quotes
+			q := strconv.QuoteRune(r)
+			b.WriteString(q[1 : len(q)-1])
+		default:
+			b.WriteRune(r)
+		}
+
+		i += size
+	}
+
+	return b.String()
+}
+
+func needsEscaping(p string) bool {
+	if !utf8.ValidString(p) {
+		return true
+	}
+
+	for _, r := range p {
+		if shouldEscape(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func shouldEscape(r rune) bool {
+	switch {
+	case r < 0x20 || r == 0x7f || r == '\\' && filepath.Separator != '\\':
+		return true
+	case r < 0x80:
+		return false
+	}
+
+	return unicode.IsControl(r) || unicode.In(r, unicode.Zl, unicode.Zp, unicode.Bidi_Control)
+}
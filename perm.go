@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// parsePerms parses a comma-separated list of permissions like o+w or u+s, or octal ones
+// like 4000, into the sets of mode bits a file has to have all of.
+func parsePerms(list string) ([]fs.FileMode, error) {
+	var perms []fs.FileMode
+
+	for _, clause := range strings.Split(list, ",") {
+		if clause = strings.TrimSpace(clause); clause == "" {
+			continue
+		}
+
+		perm, err := parsePerm(clause)
+		if err != nil {
+			return nil, err
+		}
+
+		perms = append(perms, perm)
+	}
+
+	return perms, nil
+}
+
+func parsePerm(clause string) (fs.FileMode, error) {
+	if octal, err := strconv.ParseUint(clause, 8, 12); err == nil {
+		perm := fs.FileMode(octal) & fs.ModePerm
+		if octal&0o4000 != 0 {
+			perm |= fs.ModeSetuid
+		}
+		if octal&0o2000 != 0 {
+			perm |= fs.ModeSetgid
+		}
+		if octal&0o1000 != 0 {
+			perm |= fs.ModeSticky
+		}
+
+		return perm, nil
+	}
+
+	who, bits, ok := strings.Cut(clause, "+")
+	if !ok || bits == "" || strings.Trim(who, "ugoa") != "" {
+		return 0, fmt.Errorf("invalid permission '%v', expected one like o+w, u+s or 4000", clause)
+	}
+	if who == "" || strings.Contains(who, "a") {
+		who = "ugo"
+	}
+
+	var perm fs.FileMode
+	for _, bit := range bits {
+		for _, w := range who {
+			// the permission bits of the user, the group and others are 3 bits apart
+			shift := map[rune]uint{'u': 6, 'g': 3, 'o': 0}[w]
+
+			switch bit {
+			case 'r':
+				perm |= 0o4 << shift
+			case 'w':
+				perm |= 0o2 << shift
+			case 'x':
+				perm |= 0o1 << shift
+			case 's':
+				switch w {
+				case 'u':
+					perm |= fs.ModeSetuid
+				case 'g':
+					perm |= fs.ModeSetgid
+				}
+			case 't':
+				perm |= fs.ModeSticky
+			default:
+				return 0, fmt.Errorf("invalid permission '%v', expected r, w, x, s or t after +", clause)
+			}
+		}
+	}
+
+	if perm == 0 {
+		return 0, fmt.Errorf("permission '%v' does not name any bits", clause)
+	}
+
+	return perm, nil
+}
+
+// isReportedPerm tells whether a file may be reported given -perm, it has to have all the
+// bits of one of the permissions. Directories are reported whatever their permissions.
+func (v *visualiser) isReportedPerm(e *entry) bool {
+	if e.typ != entryFile || len(v.perms) == 0 {
+		return true
+	}
+
+	for _, perm := range v.perms {
+		if e.mode&perm == perm {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lsMode renders the permissions of a file the way ls does, setuid, setgid and sticky
+// bits showing in place of the execute bits.
+func lsMode(mode fs.FileMode) string {
+	b := []byte(mode.Perm().String())
+
+	for _, special := range []struct {
+		bit fs.FileMode
+		at  int
+		set byte
+	}{
+		{fs.ModeSetuid, 3, 's'},
+		{fs.ModeSetgid, 6, 's'},
+		{fs.ModeSticky, 9, 't'},
+	} {
+		if mode&special.bit == 0 {
+			continue
+		}
+
+		if b[special.at] == 'x' {
+			b[special.at] = special.set
+		} else {
+			b[special.at] = special.set - 'a' + 'A'
+		}
+	}
+
+	return string(b)
+}
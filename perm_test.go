@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io/fs"
+	"testing"
+)
+
+func TestParsePerm(t *testing.T) {
+	tests := []struct {
+		clause  string
+		want    fs.FileMode
+		wantErr bool
+	}{
+		{clause: "o+w", want: 0o002},
+		{clause: "u+x", want: 0o100},
+		{clause: "g+rw", want: 0o060},
+		{clause: "ug+x", want: 0o110},
+		{clause: "+x", want: 0o111},
+		{clause: "a+r", want: 0o444},
+		{clause: "u+s", want: fs.ModeSetuid},
+		{clause: "g+s", want: fs.ModeSetgid},
+		{clause: "+t", want: fs.ModeSticky},
+		{clause: "755", want: 0o755},
+		{clause: "4000", want: fs.ModeSetuid},
+		{clause: "2000", want: fs.ModeSetgid},
+		{clause: "1777", want: fs.ModeSticky | 0o777},
+		{clause: "o+s", wantErr: true},
+		{clause: "o-w", wantErr: true},
+		{clause: "o+", wantErr: true},
+		{clause: "x+w", wantErr: true},
+		{clause: "o+q", wantErr: true},
+		{clause: "8000", wantErr: true},
+		{clause: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePerm(tt.clause)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parsePerm(%q) = %v, want an error", tt.clause, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parsePerm(%q) failed: %v", tt.clause, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parsePerm(%q) = %v, want %v", tt.clause, got, tt.want)
+		}
+	}
+}
+
+func TestParsePerms(t *testing.T) {
+	perms, err := parsePerms("o+w, u+s,,4000")
+	if err != nil {
+		t.Fatalf("parsePerms failed: %v", err)
+	}
+
+	want := []fs.FileMode{0o002, fs.ModeSetuid, fs.ModeSetuid}
+	if len(perms) != len(want) {
+		t.Fatalf("parsePerms = %v, want %v", perms, want)
+	}
+	for i := range want {
+		if perms[i] != want[i] {
+			t.Errorf("parsePerms[%d] = %v, want %v", i, perms[i], want[i])
+		}
+	}
+
+	if _, err := parsePerms("o+w,bad"); err == nil {
+		t.Errorf("parsePerms with an invalid clause succeeded, want an error")
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// excludePresets are named sets of -exclude patterns for the things commonly left out
+// of scans.
+var excludePresets = map[string][]string{
+	// dev leaves out dependencies, build outputs and version control data of projects
+	"dev": {"node_modules", ".git", "target", "__pycache__", ".venv"},
+	// linux-system leaves out the virtual and runtime file systems of a Linux root
+	"linux-system": {"/proc", "/sys", "/dev", "/run"},
+}
+
+// presetPatterns returns the patterns of the named preset.
+func presetPatterns(name string) ([]string, error) {
+	patterns, ok := excludePresets[name]
+	if !ok {
+		names := make([]string, 0, len(excludePresets))
+		for name := range excludePresets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("unknown preset '%v', expected one of: %v", name, strings.Join(names, ", "))
+	}
+
+	return patterns, nil
+}
@@ -0,0 +1,10 @@
+//go:build darwin || freebsd
+
+package main
+
+import "syscall"
+
+// lowerPriority makes the scan yield CPU to everything else running on the host.
+func lowerPriority() error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, 20)
+}
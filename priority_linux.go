@@ -0,0 +1,26 @@
+package main
+
+import "syscall"
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+)
+
+// lowerPriority makes the scan yield both CPU and disk to everything else running on
+// the host.
+func lowerPriority() error {
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, 19); err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOPRIO_SET, ioprioWhoProcess, 0, ioprioClassIdle<<ioprioClassShift,
+	)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
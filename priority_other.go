@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd
+
+package main
+
+import "errors"
+
+func lowerPriority() error {
+	return errors.New("changing priority is not supported on this platform")
+}
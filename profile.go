@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"strings"
+	"time"
+)
+
+// profileFlags help finding out why a scan is slow.
+type profileFlags struct {
+	cpuProfile *string
+	memProfile *string
+	pprofAddr  *string
+	timings    *bool
+}
+
+func addProfileFlags(fs *flag.FlagSet) *profileFlags {
+	return &profileFlags{
+		cpuProfile: fs.String("cpuprofile", "", "write CPU profile of the run to this file"),
+		memProfile: fs.String("memprofile", "", "write heap profile to this file once the scan is over"),
+		pprofAddr:  fs.String("pprof", "", "serve pprof on this address during the run (example: localhost:6060)"),
+		timings:    fs.Bool("timings", false, "print how long each phase of the run took"),
+	}
+}
+
+// start starts the requested profiling, the returned function writes the profiles and
+// must be called once the run is over.
+func (f *profileFlags) start() (func(), error) {
+	if *f.pprofAddr != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+		go func() {
+			if err := http.ListenAndServe(*f.pprofAddr, mux); err != nil {
+				log.Printf("error: could not serve pprof: %v", err)
+			}
+		}()
+	}
+
+	var cpuProfile *os.File
+	if *f.cpuProfile != "" {
+		var err error
+
+		cpuProfile, err = os.Create(*f.cpuProfile)
+		if err != nil {
+			return nil, fmt.Errorf("could not create CPU profile: %v", err)
+		}
+
+		if err := runtimepprof.StartCPUProfile(cpuProfile); err != nil {
+			cpuProfile.Close()
+			return nil, fmt.Errorf("could not start CPU profile: %v", err)
+		}
+	}
+
+	return func() {
+		if cpuProfile != nil {
+			runtimepprof.StopCPUProfile()
+			cpuProfile.Close()
+		}
+
+		if *f.memProfile != "" {
+			if err := writeMemProfile(*f.memProfile); err != nil {
+				log.Printf("error: could not write heap profile: %v", err)
+			}
+		}
+	}, nil
+}
+
+func writeMemProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	runtime.GC()
+
+	if err := runtimepprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+type phaseTiming struct {
+	phase    string
+	duration time.Duration
+}
+
+// phaseTimings records how long each phase of a run took.
+type phaseTimings struct {
+	phases []phaseTiming
+}
+
+// measure records the phase that started at start and is over now, it is meant to be
+// deferred.
+func (t *phaseTimings) measure(phase string, start time.Time) {
+	t.phases = append(t.phases, phaseTiming{phase, time.Since(start)})
+}
+
+func (t *phaseTimings) String() string {
+	var total time.Duration
+
+	parts := make([]string, 0, len(t.phases)+1)
+	for _, p := range t.phases {
+		parts = append(parts, fmt.Sprintf("%v %v", p.phase, p.duration.Round(time.Microsecond)))
+		total += p.duration
+	}
+	parts = append(parts, fmt.Sprintf("total %v", total.Round(time.Microsecond)))
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const progressInterval = 200 * time.Millisecond
+
+// scanProgress is notified about the scan as it goes in order to show its progress.
+type scanProgress interface {
+	start()
+	stop()
+	enterDir(dir *entry)
+	addFile(e *entry)
+	// writer wraps w so that writing to it does not mess up what is shown.
+	writer(w io.Writer) io.Writer
+}
+
+// noProgress is used when no progress should be shown.
+type noProgress struct{}
+
+func (noProgress) start()                       {}
+func (noProgress) stop()                        {}
+func (noProgress) enterDir(dir *entry)          {}
+func (noProgress) addFile(e *entry)             {}
+func (noProgress) writer(w io.Writer) io.Writer { return w }
+
+// progress shows a live status line of the scan on a terminal.
+type progress struct {
+	w io.Writer
+	display
+
+	dirs    atomic.Int64
+	bytes   atomic.Int64
+	current atomic.Pointer[string]
+
+	mu      sync.Mutex
+	shown   bool
+	started time.Time
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newProgress(w io.Writer, d display) *progress {
+	return &progress{
+		w:       w,
+		display: d,
+	}
+}
+
+func (p *progress) start() {
+	p.started = time.Now()
+	p.done = make(chan struct{})
+	p.wg.Add(1)
+
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.draw()
+			}
+		}
+	}()
+}
+
+func (p *progress) stop() {
+	close(p.done)
+	p.wg.Wait()
+
+	p.mu.Lock()
+	p.clear()
+	p.mu.Unlock()
+}
+
+func (p *progress) enterDir(dir *entry) {
+	p.dirs.Add(1)
+	p.current.Store(&dir.path)
+}
+
+func (p *progress) addFile(e *entry) {
+	p.bytes.Add(e.size)
+}
+
+func (p *progress) status() string {
+	current := ""
+	if c := p.current.Load(); c != nil {
+		current = *c
+	}
+
+	return fmt.Sprintf("scanning: %d dirs, %s, %v elapsed, %s",
+		p.dirs.Load(), p.size(p.bytes.Load()),
+		time.Since(p.started).Truncate(time.Second), current,
+	)
+}
+
+func (p *progress) draw() {
+	width := tuiDefaultWidth
+	if w, _, err := terminalSize(os.Stderr.Fd()); err == nil && w > 0 {
+		width = w
+	}
+
+	line := p.status()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fmt.Fprint(p.w, "\r"+truncate(line, width-1)+ansiClearLine)
+	p.shown = true
+}
+
+// clear removes the status line, p.mu must be held.
+func (p *progress) clear() {
+	if p.shown {
+		fmt.Fprint(p.w, "\r"+ansiClearLine)
+		p.shown = false
+	}
+}
+
+// writer wraps w so that the status line is removed before anything is written to w,
+// otherwise the output would be mixed with the status line.
+func (p *progress) writer(w io.Writer) io.Writer {
+	return &progressWriter{p: p, w: w}
+}
+
+type progressWriter struct {
+	p *progress
+	w io.Writer
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	pw.p.mu.Lock()
+	defer pw.p.mu.Unlock()
+
+	pw.p.clear()
+
+	return pw.w.Write(b)
+}
@@ -0,0 +1,204 @@
+//go:build linux && (amd64 || arm64)
+
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	rawReadDirBufSize = 64 << 10
+	atSymlinkNofollow = 0x100
+)
+
+// rawReadDirBufs keeps the buffers for getdents64, so that reading a directory does not
+// allocate one every time.
+var rawReadDirBufs = sync.Pool{
+	New: func() any {
+		buf := make([]byte, rawReadDirBufSize)
+		return &buf
+	},
+}
+
+// readDirRaw reads the directory with getdents64 and stats its entries with fstatat
+// relative to the directory, so that the kernel does not resolve the whole path for
+// every entry. The directory itself is opened with openat relative to its parent for
+// the same reason. Entries are sorted by name the way os.ReadDir does.
+func readDirRaw(path string, parent *openDir, mode dirReadMode) ([]listedEntry, fs.FileInfo, *openDir, error) {
+	const flags = syscall.O_RDONLY | syscall.O_DIRECTORY | syscall.O_CLOEXEC | syscall.O_NOFOLLOW
+
+	var fd int
+	var err error
+	if parent != nil {
+		fd, err = syscall.Openat(parent.fd, filepath.Base(path), flags, 0)
+	} else {
+		fd, err = syscall.Open(path, flags&^syscall.O_NOFOLLOW, 0)
+	}
+	if err != nil {
+		return nil, nil, nil, &fs.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	var dir *openDir
+	if mode.keepOpen {
+		dir = &openDir{fd: fd}
+	}
+	defer func() {
+		if dir == nil {
+			syscall.Close(fd)
+		}
+	}()
+
+	var info fs.FileInfo
+	if mode.statSelf {
+		self := &rawFileInfo{name: filepath.Base(path)}
+		if err := syscall.Fstat(fd, &self.st); err == nil {
+			info = self
+		}
+	}
+
+	var entries []listedEntry
+
+	bufp := rawReadDirBufs.Get().(*[]byte)
+	defer rawReadDirBufs.Put(bufp)
+
+	buf := *bufp
+	for {
+		n, err := syscall.Getdents(fd, buf)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			dir = nil
+			return nil, nil, nil, &fs.PathError{Op: "getdents64", Path: path, Err: err}
+		}
+		if n <= 0 {
+			break
+		}
+
+		for off := 0; off < n; {
+			// struct linux_dirent64: ino, off, reclen, type, name
+			reclen := int(*(*uint16)(unsafe.Pointer(&buf[off+16])))
+			typ := buf[off+18]
+			name := buf[off+19 : off+reclen]
+			for i, c := range name {
+				if c == 0 {
+					name = name[:i]
+					break
+				}
+			}
+			off += reclen
+
+			if string(name) == "." || string(name) == ".." {
+				continue
+			}
+
+			entries = append(entries, statAt(fd, string(name), typ, mode))
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	return entries, info, dir, nil
+}
+
+func (d *openDir) close() {
+	syscall.Close(d.fd)
+}
+
+// statAt makes a listed entry out of a dirent, only regular files and entries of unknown
+// type are stat-ed, directories are stat-ed if the mode asks for it.
+func statAt(fd int, name string, typ byte, mode dirReadMode) listedEntry {
+	e := &rawDirEntry{name: name}
+
+	switch typ {
+	case syscall.DT_REG, syscall.DT_UNKNOWN:
+	case syscall.DT_DIR:
+		e.typ = fs.ModeDir
+		if !mode.statSubdirs {
+			return listedEntry{DirEntry: e}
+		}
+	case syscall.DT_LNK:
+		e.typ = fs.ModeSymlink
+		return listedEntry{DirEntry: e}
+	case syscall.DT_SOCK:
+		e.typ = fs.ModeSocket
+		return listedEntry{DirEntry: e}
+	case syscall.DT_FIFO:
+		e.typ = fs.ModeNamedPipe
+		return listedEntry{DirEntry: e}
+	case syscall.DT_CHR:
+		e.typ = fs.ModeDevice | fs.ModeCharDevice
+		return listedEntry{DirEntry: e}
+	case syscall.DT_BLK:
+		e.typ = fs.ModeDevice
+		return listedEntry{DirEntry: e}
+	default:
+		e.typ = fs.ModeIrregular
+		return listedEntry{DirEntry: e}
+	}
+
+	var st syscall.Stat_t
+	if err := fstatat(fd, name, &st, atSymlinkNofollow); err != nil {
+		e.err = &fs.PathError{Op: "fstatat", Path: name, Err: err}
+
+		return listedEntry{DirEntry: e, infoErr: e.err}
+	}
+
+	e.info = &rawFileInfo{name: name, st: st}
+	e.typ = e.info.Mode().Type()
+
+	return listedEntry{DirEntry: e, info: e.info}
+}
+
+type rawDirEntry struct {
+	name string
+	typ  fs.FileMode
+	info fs.FileInfo
+	err  error
+}
+
+func (e *rawDirEntry) Name() string               { return e.name }
+func (e *rawDirEntry) IsDir() bool                { return e.typ.IsDir() }
+func (e *rawDirEntry) Type() fs.FileMode          { return e.typ }
+func (e *rawDirEntry) Info() (fs.FileInfo, error) { return e.info, e.err }
+
+type rawFileInfo struct {
+	name string
+	st   syscall.Stat_t
+}
+
+func (i *rawFileInfo) Name() string       { return i.name }
+func (i *rawFileInfo) Size() int64        { return i.st.Size }
+func (i *rawFileInfo) IsDir() bool        { return i.Mode().IsDir() }
+func (i *rawFileInfo) Sys() any           { return &i.st }
+func (i *rawFileInfo) ModTime() time.Time { return time.Unix(i.st.Mtim.Unix()) }
+
+func (i *rawFileInfo) Mode() fs.FileMode {
+	mode := fs.FileMode(i.st.Mode & 0o777)
+
+	switch i.st.Mode & syscall.S_IFMT {
+	case syscall.S_IFDIR:
+		mode |= fs.ModeDir
+	case syscall.S_IFLNK:
+		mode |= fs.ModeSymlink
+	case syscall.S_IFREG:
+	case syscall.S_IFSOCK:
+		mode |= fs.ModeSocket
+	case syscall.S_IFIFO:
+		mode |= fs.ModeNamedPipe
+	case syscall.S_IFCHR:
+		mode |= fs.ModeDevice | fs.ModeCharDevice
+	case syscall.S_IFBLK:
+		mode |= fs.ModeDevice
+	default:
+		mode |= fs.ModeIrregular
+	}
+
+	return mode
+}
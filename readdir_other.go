@@ -0,0 +1,13 @@
+//go:build !linux || !(amd64 || arm64)
+
+package main
+
+import "io/fs"
+
+// readDirRaw falls back to the portable way of reading directories.
+func readDirRaw(path string, parent *openDir, mode dirReadMode) ([]listedEntry, fs.FileInfo, *openDir, error) {
+	return readDirStat(path, parent, mode)
+}
+
+// close does nothing, directories are never kept open by the portable reader.
+func (d *openDir) close() {}
@@ -0,0 +1,6 @@
+package main
+
+// irregularLinks is set where Go reports links other than symbolic ones as irregular
+// files: on Windows junctions and other mount points are not symbolic links, scanning
+// C:\ by following them would run into loops like "Documents and Settings".
+const irregularLinks = true
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+)
+
+const progName = "space_visualiser"
+
+const (
+	formatText     = "text"
+	formatJSON     = "json"
+	formatCSV      = "csv"
+	formatNDJSON   = "ndjson"
+	formatNCDU     = "ncdu"
+	formatHTML     = "html"
+	formatSVG      = "svg-treemap"
+	formatDOT      = "dot"
+	formatMarkdown = "markdown"
+	formatYAML     = "yaml"
+	formatFolded   = "folded"
+	formatDU       = "du"
+	formatXML      = "xml"
+	formatTree     = "tree"
+	formatPlist    = "plist"
+	formatParquet  = "parquet"
+	formatTSV      = "tsv"
+	formatTemplate = "template"
+	formatSunburst = "sunburst"
+	formatXLSX     = "xlsx"
+	formatMsgpack  = "msgpack"
+	formatTUI      = "tui"
+	formatBars     = "bars"
+)
+
+var formats = []string{
+	formatText, formatJSON, formatNDJSON, formatCSV, formatTSV, formatNCDU, formatHTML,
+	formatSVG, formatDOT, formatMarkdown, formatYAML, formatFolded, formatDU, formatXML,
+	formatTree, formatPlist, formatParquet, formatTemplate, formatSunburst,
+	formatXLSX, formatMsgpack, formatTUI, formatBars,
+}
+
+// streamingFormats write entries as soon as they are reported and keep no more than the
+// state of the directories being scanned.
+var streamingFormats = map[string]bool{
+	formatText:     true,
+	formatCSV:      true,
+	formatNDJSON:   true,
+	formatMarkdown: true,
+	formatDU:       true,
+	formatTSV:      true,
+	formatTemplate: true,
+}
+
+// tableFormats are read by people, the tables printed once the scan is over are appended
+// to the report in these formats rather than written to stderr.
+var tableFormats = map[string]bool{
+	formatText: true,
+	formatTree: true,
+	formatBars: true,
+}
+
+// reportOptions holds settings specific to some of the output formats.
+type reportOptions struct {
+	// columns is the list of columns printed by the tsv format.
+	columns string
+	// template is the text/template executed by the template format.
+	template string
+	// colors paints entries in the text and tree formats, may be nil.
+	colors *colorizer
+	// width is the width of the terminal the report is written to.
+	width int
+	// otherSize names the size of entries other than the one they are accounted by,
+	// empty if it is unknown. The text format prints it for sparse files and, if
+	// bothSizes is set, for every entry.
+	otherSize string
+	bothSizes bool
+	// counts prints the numbers of files and directories within directories in the text
+	// format.
+	counts bool
+	// diskUsage tells that entries are sized by the space they take on disk.
+	diskUsage bool
+	// shared prints the disk usage of entries split into the exclusive and the shared
+	// parts in the text format.
+	shared bool
+	// mtimes and atimes print the modification and access times of files in the text
+	// format.
+	mtimes bool
+	atimes bool
+	// modes prints the modes of files in the text format.
+	modes bool
+
+	display
+	// flat tells that entries are not reported directory by directory, so the text
+	// format does not group files with empty lines.
+	flat bool
+}
+
+// display renders paths and names in the line-oriented formats, where a newline in a
+// name would break the report, and sizes of entries for people.
+type display struct {
+	// paths renders paths the way the output flags ask for, they are escaped if it is nil.
+	paths func(string) string
+	// raw tells that paths are not escaped, -raw-paths only normalizes them if asked to.
+	raw bool
+	// inodes tells that entries are sized by the numbers of inodes they take.
+	inodes bool
+}
+
+func (d display) path(p string) string {
+	if d.paths == nil {
+		return escapePath(p)
+	}
+
+	return d.paths(p)
+}
+
+// size formats sizes of entries for people, with humanInodes if entries are sized by the
+// numbers of inodes they take and with humanBytes otherwise.
+func (d display) size(size int64) string {
+	if d.inodes {
+		return humanInodes(size)
+	}
+
+	return humanBytes(size)
+}
+
+// reporter receives results of a scan and renders them in some output format.
+type reporter interface {
+	// report is called for every entry exceeding the size threshold as soon as its size
+	// is known, so children are always reported before their parent directory.
+	report(e *entry)
+	// dirDone is called once all the contents of the directory have been processed.
+	dirDone(dir *entry)
+	// finish is called once the scan is over.
+	finish(root *entry) error
+}
+
+// treeNeeder is implemented by reporters that render the whole tree at once and thus
+// need the visualiser to keep every scanned entry in memory.
+type treeNeeder interface {
+	needsTree() bool
+}
+
+// mtimeNeeder is implemented by reporters that can tell whether they print modification
+// times, directories are not stat-ed at all for the reporters that do not.
+type mtimeNeeder interface {
+	needsMtime() bool
+}
+
+func newReporter(format string, w io.Writer, opts reportOptions) (reporter, error) {
+	switch format {
+	case formatText:
+		return newTextReporter(w, opts), nil
+	case formatJSON:
+		return newTreeReporter(w, writeJSON), nil
+	case formatCSV:
+		return newCSVReporter(w, opts.display), nil
+	case formatNDJSON:
+		return newNDJSONReporter(w), nil
+	case formatNCDU:
+		return newTreeReporter(w, writeNCDU), nil
+	case formatHTML:
+		return newTreeReporter(w, func(w io.Writer, root *entry) error {
+			return writeHTML(w, root, opts.display)
+		}), nil
+	case formatSVG:
+		return newTreeReporter(w, func(w io.Writer, root *entry) error {
+			return writeSVGTreemap(w, root, opts.display)
+		}), nil
+	case formatDOT:
+		return newTreeReporter(w, func(w io.Writer, root *entry) error {
+			return writeDOT(w, root, opts.display)
+		}), nil
+	case formatMarkdown:
+		return newMarkdownReporter(w, opts.display), nil
+	case formatYAML:
+		return newTreeReporter(w, writeYAML), nil
+	case formatFolded:
+		return newTreeReporter(w, func(w io.Writer, root *entry) error {
+			return writeFolded(w, root, opts.display)
+		}), nil
+	case formatDU:
+		return newDUReporter(w, !opts.diskUsage && opts.otherSize != "", opts.display), nil
+	case formatXML:
+		return newTreeReporter(w, writeXML), nil
+	case formatTree:
+		return newTreeReporter(w, func(w io.Writer, root *entry) error {
+			return writeTree(w, root, opts.colors, opts.display)
+		}), nil
+	case formatPlist:
+		return newTreeReporter(w, writePlist), nil
+	case formatParquet:
+		return newParquetReporter(w), nil
+	case formatTSV:
+		return newTSVReporter(w, opts.columns, opts.display)
+	case formatTemplate:
+		return newTemplateReporter(w, opts.template, opts.display)
+	case formatSunburst:
+		return newTreeReporter(w, func(w io.Writer, root *entry) error {
+			return writeSunburst(w, root, opts.display)
+		}), nil
+	case formatXLSX:
+		return newXLSXReporter(w, opts.display), nil
+	case formatMsgpack:
+		return newTreeReporter(w, writeMsgpack), nil
+	case formatBars:
+		return newBarsReporter(w, opts.width, opts.colors, opts.display), nil
+	case formatTUI:
+		return newTreeReporter(w, func(w io.Writer, root *entry) error { return runTUI(root, opts.display) }), nil
+	default:
+		return nil, fmt.Errorf("unknown output format '%v'", format)
+	}
+}
+
+// treeReporter postpones all the output till the end of the scan and then renders the
+// whole tree with the given function.
+type treeReporter struct {
+	w      io.Writer
+	render func(w io.Writer, root *entry) error
+}
+
+func newTreeReporter(w io.Writer, render func(w io.Writer, root *entry) error) *treeReporter {
+	return &treeReporter{
+		w:      w,
+		render: render,
+	}
+}
+
+func (r *treeReporter) needsTree() bool    { return true }
+func (r *treeReporter) report(e *entry)    {}
+func (r *treeReporter) dirDone(dir *entry) {}
+
+func (r *treeReporter) finish(root *entry) error {
+	markShown(root)
+
+	return r.render(r.w, root)
+}
+
+// markShown marks the flagged entries within the tree together with the directories
+// leading to them, it tells whether the entry is shown.
+func markShown(e *entry) bool {
+	e.shown = e.flagged
+	for _, child := range e.children {
+		if markShown(child) {
+			e.shown = true
+		}
+	}
+
+	return e.shown
+}
+
+var humanSizeSuffixes = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+
+// humanBytes formats the size exactly the way humanize.BigBytes does, without allocating
+// big integers for every printed entry.
+func humanBytes(size int64) string {
+	if size < 10 {
+		return strconv.FormatInt(size, 10) + " B"
+	}
+
+	n, rem, mag := size, int64(0), 0
+	for n >= 1000 {
+		n, rem = n/1000, n%1000
+		mag++
+	}
+
+	val := float64(n) + float64(rem)/1000
+
+	prec := 0
+	if val < 10 {
+		prec = 1
+	}
+
+	return strconv.FormatFloat(val, 'f', prec, 64) + " " + humanSizeSuffixes[mag]
+}
+
+// humanInodes formats the number of inodes taken by an entry.
+func humanInodes(n int64) string {
+	if n == 1 {
+		return "1 inode"
+	}
+
+	return humanize.Comma(n) + " inodes"
+}
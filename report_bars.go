@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	barsSizeWidth = 10
+	barsMinWidth  = 10
+)
+
+// barsReporter collects entries exceeding the size threshold and prints them with bars
+// proportional to their sizes once the scan is over. Bars are scaled so that the largest
+// entry takes all the space left on the terminal.
+type barsReporter struct {
+	w       io.Writer
+	width   int
+	colors  *colorizer
+	entries []*entry
+
+	display
+}
+
+func newBarsReporter(w io.Writer, width int, colors *colorizer, d display) *barsReporter {
+	return &barsReporter{
+		w:       w,
+		width:   width,
+		colors:  colors,
+		display: d,
+	}
+}
+
+func (r *barsReporter) report(e *entry) {
+	r.entries = append(r.entries, e)
+}
+
+func (r *barsReporter) dirDone(dir *entry) {}
+
+func (r *barsReporter) finish(root *entry) error {
+	largest, longestPath := int64(0), 0
+
+	for _, e := range r.entries {
+		largest = max(largest, e.size)
+		longestPath = max(longestPath, utf8.RuneCountInString(r.path(e.path)))
+	}
+
+	barWidth := max(barsMinWidth, r.width-barsSizeWidth-longestPath-2)
+
+	bw := bufio.NewWriter(r.w)
+
+	for _, e := range r.entries {
+		filled := 0
+		if largest > 0 {
+			filled = int(float64(e.size)/float64(largest)*float64(barWidth) + 0.5)
+		}
+
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+
+		fmt.Fprintf(bw, "%*s %s %s\n", barsSizeWidth, r.size(e.size), r.colors.paint(e.size, bar), r.path(e.path))
+	}
+
+	return bw.Flush()
+}
+
+func (r *barsReporter) needsMtime() bool { return false }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+)
+
+// csvReporter writes one row per entry exceeding the size threshold.
+type csvReporter struct {
+	w *csv.Writer
+
+	display
+}
+
+func newCSVReporter(w io.Writer, d display) *csvReporter {
+	r := &csvReporter{
+		w:       csv.NewWriter(w),
+		display: d,
+	}
+
+	r.write([]string{"path", "type", "size", "human_size", "parent"})
+
+	return r
+}
+
+func (r *csvReporter) write(record []string) {
+	if err := r.w.Write(record); err != nil {
+		log.Printf("error: could not write csv record: %v", err)
+	}
+}
+
+func (r *csvReporter) report(e *entry) {
+	r.write([]string{
+		e.path,
+		e.typ.String(),
+		strconv.FormatInt(e.size, 10),
+		r.size(e.size),
+		filepath.Dir(e.path),
+	})
+}
+
+func (r *csvReporter) dirDone(dir *entry) {}
+
+func (r *csvReporter) finish(root *entry) error {
+	r.w.Flush()
+
+	return r.w.Error()
+}
+
+func (r *csvReporter) needsMtime() bool { return false }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeDOT renders entries exceeding the size threshold as a Graphviz graph, every node
+// is labeled with the name and the size of the entry.
+func writeDOT(w io.Writer, root *entry, d display) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph space {")
+	fmt.Fprintln(bw, "\trankdir=LR;")
+	fmt.Fprintln(bw, "\tnode [shape=box, fontname=\"sans-serif\"];")
+
+	if root.shown {
+		id := 0
+		writeDOTEntry(bw, root, &id, root.path, d)
+	}
+
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+func writeDOTEntry(w io.Writer, e *entry, id *int, name string, d display) int {
+	nodeID := *id
+	*id++
+
+	shape := "box"
+	if e.typ == entryFile {
+		shape = "note"
+	}
+
+	fmt.Fprintf(w, "\tn%d [label=%s, shape=%s];\n",
+		nodeID, dotQuote(name+"\n"+d.size(e.size)), shape,
+	)
+
+	for _, child := range e.children {
+		if !child.shown {
+			continue
+		}
+
+		childID := writeDOTEntry(w, child, id, child.name, d)
+		fmt.Fprintf(w, "\tn%d -> n%d;\n", nodeID, childID)
+	}
+
+	return nodeID
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+const duBlockSize = 1024
+
+// duReporter prints entries exceeding the size threshold in the same format as 'du -k'
+// does, or 'du --inodes' if entries are sized by inodes, so that the output can be
+// consumed by scripts written for du. Like du, it prints the space entries take on disk
+// even if they are sized by their apparent sizes.
+type duReporter struct {
+	w io.Writer
+	// apparent tells that entries are sized by their apparent sizes, the space they take
+	// on disk is their other size then.
+	apparent bool
+
+	display
+}
+
+func newDUReporter(w io.Writer, apparent bool, d display) *duReporter {
+	return &duReporter{
+		w:        w,
+		apparent: apparent,
+		display:  d,
+	}
+}
+
+func (r *duReporter) report(e *entry) {
+	if r.inodes {
+		fmt.Fprintf(r.w, "%d\t%s\n", e.size, r.path(e.path))
+		return
+	}
+
+	size := e.size
+	if r.apparent {
+		size = e.otherSize
+	}
+
+	// du rounds sizes up to the next block
+	fmt.Fprintf(r.w, "%d\t%s\n", (size+duBlockSize-1)/duBlockSize, r.path(e.path))
+}
+
+func (r *duReporter) dirDone(dir *entry) {}
+
+func (r *duReporter) finish(root *entry) error {
+	return nil
+}
+
+func (r *duReporter) needsMtime() bool { return false }
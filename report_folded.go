@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeFolded renders the scanned tree in the folded stacks format consumed by
+// flamegraph.pl. Entries exceeding the size threshold get frames of their own, while the
+// rest of the contents of a directory is attributed to the frame of the directory itself.
+func writeFolded(w io.Writer, root *entry, d display) error {
+	bw := bufio.NewWriter(w)
+
+	writeFoldedEntry(bw, root, foldedFrame(d.path(root.path)), d)
+
+	return bw.Flush()
+}
+
+func writeFoldedEntry(w io.Writer, dir *entry, stack string, d display) {
+	own := dir.size
+
+	for _, child := range dir.children {
+		if !child.shown {
+			continue
+		}
+
+		own -= child.size
+		childStack := stack + ";" + foldedFrame(d.path(child.name))
+
+		if child.typ == entryDir {
+			writeFoldedEntry(w, child, childStack, d)
+		} else {
+			fmt.Fprintf(w, "%s %d\n", childStack, child.size)
+		}
+	}
+
+	if own > 0 {
+		fmt.Fprintf(w, "%s %d\n", stack, own)
+	}
+}
+
+var foldedEscaper = strings.NewReplacer(";", "_", "\n", " ")
+
+func foldedFrame(name string) string {
+	return foldedEscaper.Replace(name)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"sort"
+	"time"
+)
+
+type htmlNode struct {
+	Path      string
+	Name      string
+	Type      string
+	Size      int64
+	HumanSize string
+	Children  []*htmlNode
+}
+
+type htmlReport struct {
+	Root      *htmlNode
+	Rows      []*htmlNode
+	Generated string
+
+	// Treemap and Rescan are only used by the serve subcommand.
+	Treemap template.HTML
+	Rescan  bool
+}
+
+func newHTMLNode(e *entry, rows *[]*htmlNode, d display) *htmlNode {
+	n := &htmlNode{
+		Path:      e.path,
+		Name:      e.name,
+		Type:      e.typ.String(),
+		Size:      e.size,
+		HumanSize: d.size(e.size),
+	}
+
+	*rows = append(*rows, n)
+
+	for _, child := range e.children {
+		if child.shown {
+			n.Children = append(n.Children, newHTMLNode(child, rows, d))
+		}
+	}
+
+	return n
+}
+
+// writeHTML renders entries exceeding the size threshold as a standalone HTML page with
+// a collapsible directory tree and a sortable table.
+func writeHTML(w io.Writer, root *entry, d display) error {
+	return htmlTemplate.Execute(w, newHTMLReport(root, time.Now(), d))
+}
+
+func newHTMLReport(root *entry, generated time.Time, d display) *htmlReport {
+	report := &htmlReport{
+		Generated: generated.Format(time.RFC1123),
+	}
+
+	if root.shown {
+		report.Root = newHTMLNode(root, &report.Rows, d)
+		report.Root.Name = root.path
+	}
+
+	sort.SliceStable(report.Rows, func(i, j int) bool {
+		return report.Rows[i].Size > report.Rows[j].Size
+	})
+
+	return report
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>space_visualiser report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 0.2em 0.8em; text-align: left; border-bottom: 1px solid #ddd; }
+th { cursor: pointer; background: #f0f0f0; }
+td.size { text-align: right; }
+details { margin-left: 1.2em; }
+.file { margin-left: 2.4em; }
+</style>
+</head>
+<body>
+<h1>space_visualiser report</h1>
+<p>Generated {{.Generated}}</p>
+{{if .Rescan}}<form method="post" action="/rescan"><button type="submit">Rescan</button></form>{{end}}
+{{with .Treemap}}<h2>Treemap</h2>
+{{.}}
+{{end}}{{with .Root}}
+<h2>Tree</h2>
+{{template "node" .}}
+{{else}}
+<p>No entries exceed the threshold.</p>
+{{end}}
+{{if .Rows}}
+<h2>Entries</h2>
+<table id="entries">
+<thead><tr><th data-type="string">Path</th><th data-type="string">Type</th><th data-type="number">Size</th></tr></thead>
+<tbody>
+{{range .Rows}}<tr><td>{{.Path}}</td><td>{{.Type}}</td><td class="size" data-value="{{.Size}}">{{.HumanSize}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+<script>
+document.querySelectorAll("#entries th").forEach(function (th, column) {
+  var descending = false;
+  th.addEventListener("click", function () {
+    var tbody = document.querySelector("#entries tbody");
+    var rows = Array.prototype.slice.call(tbody.rows);
+    descending = !descending;
+    rows.sort(function (a, b) {
+      var x = a.cells[column], y = b.cells[column], res;
+      if (th.dataset.type === "number") {
+        res = Number(x.dataset.value) - Number(y.dataset.value);
+      } else {
+        res = x.textContent.localeCompare(y.textContent);
+      }
+      return descending ? -res : res;
+    });
+    rows.forEach(function (row) { tbody.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+{{define "node"}}{{if eq .Type "dir"}}<details open><summary>{{.Name}} &mdash; {{.HumanSize}}</summary>
+{{range .Children}}{{template "node" .}}{{end}}</details>
+{{else}}<div class="file">{{.Name}} &mdash; {{.HumanSize}}</div>
+{{end}}{{end}}
+`))
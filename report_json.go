@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+)
+
+type jsonEntry struct {
+	Path     string       `json:"path"`
+	Name     string       `json:"name"`
+	Type     string       `json:"type"`
+	Size     int64        `json:"size"`
+	Children []*jsonEntry `json:"children,omitempty"`
+}
+
+func newJSONEntry(e *entry) *jsonEntry {
+	je := &jsonEntry{
+		Path: e.path,
+		Name: e.name,
+		Type: e.typ.String(),
+		Size: e.size,
+	}
+
+	for _, child := range e.children {
+		je.Children = append(je.Children, newJSONEntry(child))
+	}
+
+	return je
+}
+
+// writeJSON renders the whole scanned tree as one JSON document.
+func writeJSON(w io.Writer, root *entry) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(newJSONEntry(root))
+}
+
+// ndjsonReporter emits every entry exceeding the size threshold as a separate JSON object
+// on its own line as soon as it is found.
+type ndjsonReporter struct {
+	enc *json.Encoder
+}
+
+func newNDJSONReporter(w io.Writer) *ndjsonReporter {
+	return &ndjsonReporter{
+		enc: json.NewEncoder(w),
+	}
+}
+
+func (r *ndjsonReporter) report(e *entry) {
+	je := &jsonEntry{
+		Path: e.path,
+		Name: e.name,
+		Type: e.typ.String(),
+		Size: e.size,
+	}
+
+	if err := r.enc.Encode(je); err != nil {
+		log.Printf("error: could not write json record: %v", err)
+	}
+}
+
+func (r *ndjsonReporter) dirDone(dir *entry) {}
+
+func (r *ndjsonReporter) finish(root *entry) error {
+	return nil
+}
+
+func (r *ndjsonReporter) needsMtime() bool { return false }
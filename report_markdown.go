@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// markdownReporter renders entries exceeding the size threshold as Markdown tables, one
+// table per directory. A table is printed as soon as the directory is fully scanned.
+type markdownReporter struct {
+	w       io.Writer
+	pending map[*entry][]*entry
+
+	display
+}
+
+func newMarkdownReporter(w io.Writer, d display) *markdownReporter {
+	return &markdownReporter{
+		w:       w,
+		pending: make(map[*entry][]*entry),
+		display: d,
+	}
+}
+
+func (r *markdownReporter) report(e *entry) {
+	if e.parent == nil {
+		return
+	}
+
+	r.pending[e.parent] = append(r.pending[e.parent], e)
+}
+
+func (r *markdownReporter) dirDone(dir *entry) {
+	entries, ok := r.pending[dir]
+	if !ok {
+		return
+	}
+
+	delete(r.pending, dir)
+
+	fmt.Fprintf(r.w, "### `%s` (%s)\n\n", markdownEscape(r.path(dir.path)), r.size(dir.size))
+	fmt.Fprintln(r.w, "| Name | Type | Size |")
+	fmt.Fprintln(r.w, "| --- | --- | ---: |")
+
+	for _, e := range entries {
+		fmt.Fprintf(r.w, "| `%s` | %s | %s |\n", markdownEscape(r.path(e.name)), e.typ, r.size(e.size))
+	}
+
+	fmt.Fprintln(r.w)
+}
+
+func (r *markdownReporter) finish(root *entry) error {
+	_, err := fmt.Fprintf(r.w, "**Total size of `%s`: %s**\n", markdownEscape(r.path(root.path)), r.size(root.size))
+
+	return err
+}
+
+func (r *markdownReporter) needsMtime() bool { return false }
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "`", "'", "\n", " ")
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// The msgpack format stores the whole scanned tree, every entry is a map with the keys
+// path, name, type, size, mtime (in nanoseconds since the Unix epoch, 0 if it is unknown)
+// and children (only for directories). It can be turned into any other format with the decode subcommand.
+
+// writeMsgpack renders the whole scanned tree as a MessagePack document.
+func writeMsgpack(w io.Writer, root *entry) error {
+	mw := &msgpackWriter{w: bufio.NewWriter(w)}
+
+	mw.entry(root)
+
+	if mw.err != nil {
+		return mw.err
+	}
+
+	return mw.w.Flush()
+}
+
+type msgpackWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (m *msgpackWriter) write(b ...byte) {
+	if m.err != nil {
+		return
+	}
+
+	_, m.err = m.w.Write(b)
+}
+
+func (m *msgpackWriter) entry(e *entry) {
+	fields := 5
+	if e.typ == entryDir {
+		fields++
+	}
+
+	m.mapHeader(fields)
+	m.str("path")
+	m.str(e.path)
+	m.str("name")
+	m.str(e.name)
+	m.str("type")
+	m.str(e.typ.String())
+	m.str("size")
+	m.int(e.size)
+	m.str("mtime")
+	if e.mtime.IsZero() {
+		m.int(0)
+	} else {
+		m.int(e.mtime.UnixNano())
+	}
+
+	if e.typ == entryDir {
+		m.str("children")
+		m.arrayHeader(len(e.children))
+
+		for _, child := range e.children {
+			m.entry(child)
+		}
+	}
+}
+
+func (m *msgpackWriter) mapHeader(n int) {
+	switch {
+	case n < 16:
+		m.write(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		m.write(0xde, byte(n>>8), byte(n))
+	default:
+		m.write(0xdf)
+		m.write(binary.BigEndian.AppendUint32(nil, uint32(n))...)
+	}
+}
+
+func (m *msgpackWriter) arrayHeader(n int) {
+	switch {
+	case n < 16:
+		m.write(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		m.write(0xdc, byte(n>>8), byte(n))
+	default:
+		m.write(0xdd)
+		m.write(binary.BigEndian.AppendUint32(nil, uint32(n))...)
+	}
+}
+
+func (m *msgpackWriter) str(s string) {
+	switch n := len(s); {
+	case n < 32:
+		m.write(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		m.write(0xd9, byte(n))
+	case n <= math.MaxUint16:
+		m.write(0xda, byte(n>>8), byte(n))
+	default:
+		m.write(0xdb)
+		m.write(binary.BigEndian.AppendUint32(nil, uint32(n))...)
+	}
+
+	if m.err == nil {
+		_, m.err = m.w.WriteString(s)
+	}
+}
+
+func (m *msgpackWriter) int(v int64) {
+	switch {
+	case v >= 0 && v < 128:
+		m.write(byte(v))
+	case v >= 0 && v <= math.MaxUint32:
+		m.write(0xce)
+		m.write(binary.BigEndian.AppendUint32(nil, uint32(v))...)
+	default:
+		m.write(0xd3)
+		m.write(binary.BigEndian.AppendUint64(nil, uint64(v))...)
+	}
+}
+
+// msgpackMaxPrealloc limits what the reader allocates ahead for the lengths the input
+// declares, a truncated or damaged file must not make it allocate more than the file holds.
+const msgpackMaxPrealloc = 64 << 10
+
+// readMsgpack reads a tree written by writeMsgpack.
+func readMsgpack(r *bufio.Reader) (*entry, error) {
+	mr := &msgpackReader{r: r}
+
+	return mr.entry(nil)
+}
+
+type msgpackReader struct {
+	r *bufio.Reader
+}
+
+func (m *msgpackReader) entry(parent *entry) (*entry, error) {
+	fields, err := m.mapHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &entry{
+		parent: parent,
+	}
+
+	if parent != nil {
+		e.depth = parent.depth + 1
+	}
+
+	for i := 0; i < fields; i++ {
+		key, err := m.str()
+		if err != nil {
+			return nil, err
+		}
+
+		switch key {
+		case "path":
+			e.path, err = m.str()
+		case "name":
+			e.name, err = m.str()
+		case "type":
+			var typ string
+
+			if typ, err = m.str(); err == nil {
+				e.typ, err = parseEntryType(typ)
+			}
+		case "size":
+			e.size, err = m.int()
+		case "mtime":
+			var mtime int64
+
+			mtime, err = m.int()
+			if mtime != 0 {
+				e.mtime = time.Unix(0, mtime)
+			}
+		case "children":
+			err = m.children(e)
+		default:
+			err = fmt.Errorf("unknown key '%v'", key)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+func (m *msgpackReader) children(dir *entry) error {
+	n, err := m.arrayHeader()
+	if err != nil {
+		return err
+	}
+
+	dir.children = make([]*entry, 0, min(n, msgpackMaxPrealloc))
+
+	for i := 0; i < n; i++ {
+		child, err := m.entry(dir)
+		if err != nil {
+			return err
+		}
+
+		dir.children = append(dir.children, child)
+
+		// the counts are not saved, they are known once the children are read
+		dir.count(child)
+	}
+
+	return nil
+}
+
+func (m *msgpackReader) uint(size int) (uint64, error) {
+	var b [8]byte
+
+	if _, err := io.ReadFull(m.r, b[8-size:]); err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+func (m *msgpackReader) mapHeader() (int, error) {
+	b, err := m.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+
+	switch {
+	case b&0xf0 == 0x80:
+		return int(b & 0x0f), nil
+	case b == 0xde:
+		n, err = m.uint(2)
+	case b == 0xdf:
+		n, err = m.uint(4)
+	default:
+		return 0, fmt.Errorf("expected map, got 0x%x", b)
+	}
+
+	return int(n), err
+}
+
+func (m *msgpackReader) arrayHeader() (int, error) {
+	b, err := m.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+
+	switch {
+	case b&0xf0 == 0x90:
+		return int(b & 0x0f), nil
+	case b == 0xdc:
+		n, err = m.uint(2)
+	case b == 0xdd:
+		n, err = m.uint(4)
+	default:
+		return 0, fmt.Errorf("expected array, got 0x%x", b)
+	}
+
+	return int(n), err
+}
+
+func (m *msgpackReader) str() (string, error) {
+	b, err := m.r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+
+	var n uint64
+
+	switch {
+	case b&0xe0 == 0xa0:
+		n = uint64(b & 0x1f)
+	case b == 0xd9:
+		n, err = m.uint(1)
+	case b == 0xda:
+		n, err = m.uint(2)
+	case b == 0xdb:
+		n, err = m.uint(4)
+	default:
+		return "", fmt.Errorf("expected string, got 0x%x", b)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if n <= msgpackMaxPrealloc {
+		s := make([]byte, n)
+		if _, err := io.ReadFull(m.r, s); err != nil {
+			return "", err
+		}
+
+		return string(s), nil
+	}
+
+	// longer strings grow with what is actually read
+	var s strings.Builder
+	if _, err := io.CopyN(&s, m.r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return "", err
+	}
+
+	return s.String(), nil
+}
+
+func (m *msgpackReader) int() (int64, error) {
+	b, err := m.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	var v uint64
+
+	switch {
+	case b < 0x80:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b == 0xcc, b == 0xd0:
+		v, err = m.uint(1)
+		if b == 0xd0 {
+			return int64(int8(v)), err
+		}
+	case b == 0xcd, b == 0xd1:
+		v, err = m.uint(2)
+		if b == 0xd1 {
+			return int64(int16(v)), err
+		}
+	case b == 0xce, b == 0xd2:
+		v, err = m.uint(4)
+		if b == 0xd2 {
+			return int64(int32(v)), err
+		}
+	case b == 0xcf, b == 0xd3:
+		v, err = m.uint(8)
+	default:
+		return 0, fmt.Errorf("expected integer, got 0x%x", b)
+	}
+
+	return int64(v), err
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	mtime := time.Date(2024, 3, 1, 12, 30, 0, 123, time.UTC)
+
+	root := &entry{path: "/data", name: "data", typ: entryDir, size: 300_000, mtime: mtime}
+	big := &entry{path: "/data/big.bin", name: "big.bin", typ: entryFile, size: 200_000, mtime: mtime, parent: root}
+	unknown := &entry{path: "/data/unknown", name: "unknown", typ: entryFile, size: 100_000, parent: root}
+	long := &entry{path: "/data/" + strings.Repeat("x", 100_000), name: strings.Repeat("x", 100_000), typ: entryDir, parent: root}
+	root.children = []*entry{big, unknown, long}
+
+	var buf bytes.Buffer
+	if err := writeMsgpack(&buf, root); err != nil {
+		t.Fatalf("writeMsgpack failed: %v", err)
+	}
+
+	got, err := readMsgpack(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("readMsgpack failed: %v", err)
+	}
+
+	if got.path != root.path || got.typ != entryDir || got.size != root.size || !got.mtime.Equal(mtime) {
+		t.Errorf("root = %+v, want %+v", got, root)
+	}
+	if len(got.children) != len(root.children) {
+		t.Fatalf("root has %d children, want %d", len(got.children), len(root.children))
+	}
+
+	for i, want := range root.children {
+		child := got.children[i]
+
+		if child.path != want.path || child.name != want.name || child.typ != want.typ || child.size != want.size {
+			t.Errorf("child %d = %v %v %v %v, want %v %v %v %v", i,
+				child.path, child.name, child.typ, child.size, want.path, want.name, want.typ, want.size,
+			)
+		}
+		if !child.mtime.Equal(want.mtime) || child.mtime.IsZero() != want.mtime.IsZero() {
+			t.Errorf("mtime of %v = %v, want %v", want.name, child.mtime, want.mtime)
+		}
+		if child.parent != got || child.depth != 1 {
+			t.Errorf("child %v is not linked to its parent", want.name)
+		}
+	}
+
+	if got.files != 2 || got.dirs != 1 {
+		t.Errorf("root counts %d files and %d dirs, want 2 and 1", got.files, got.dirs)
+	}
+}
+
+func TestMsgpackTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeMsgpack(&buf, &entry{path: "/data", name: "data", typ: entryDir}); err != nil {
+		t.Fatalf("writeMsgpack failed: %v", err)
+	}
+
+	for n := 0; n < buf.Len(); n++ {
+		if _, err := readMsgpack(bufio.NewReader(bytes.NewReader(buf.Bytes()[:n]))); err == nil {
+			t.Errorf("reading the first %d bytes succeeded, want an error", n)
+		}
+	}
+}
+
+func TestMsgpackHugeLengths(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		// a map with the key "path" holding a string declared 4 GB long
+		{"string", []byte{0x81, 0xa4, 'p', 'a', 't', 'h', 0xdb, 0xff, 0xff, 0xff, 0xff, 'x'}},
+		// a map with the key "children" holding an array declared to have 4G entries
+		{"array", []byte{0x81, 0xa8, 'c', 'h', 'i', 'l', 'd', 'r', 'e', 'n', 0xdd, 0xff, 0xff, 0xff, 0xff}},
+	}
+
+	for _, tt := range tests {
+		if _, err := readMsgpack(bufio.NewReader(bytes.NewReader(tt.input))); err == nil {
+			t.Errorf("reading a truncated %v succeeded, want an error", tt.name)
+		}
+	}
+}
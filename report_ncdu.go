@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+)
+
+const (
+	ncduMajorVersion = 1
+	ncduMinorVersion = 0
+)
+
+type ncduEntry struct {
+	Name  string `json:"name"`
+	ASize int64  `json:"asize,omitempty"`
+}
+
+// writeNCDU renders the whole scanned tree in the ncdu JSON export format, so that it
+// can be browsed later with 'ncdu -f'.
+func writeNCDU(w io.Writer, root *entry) error {
+	bw := bufio.NewWriter(w)
+
+	header, err := json.Marshal(map[string]interface{}{
+		"progname":  progName,
+		"timestamp": time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(bw, "[%d,%d,%s,\n", ncduMajorVersion, ncduMinorVersion, header)
+
+	if err := writeNCDUEntry(bw, root, true); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(bw, "]")
+
+	return bw.Flush()
+}
+
+func writeNCDUEntry(w io.Writer, e *entry, isRoot bool) error {
+	info := ncduEntry{Name: e.name}
+	if isRoot {
+		// ncdu expects the root to carry the full path
+		info.Name = e.path
+	}
+
+	if e.typ != entryDir {
+		info.ASize = e.size
+
+		return writeNCDUInfo(w, info)
+	}
+
+	fmt.Fprint(w, "[")
+
+	if err := writeNCDUInfo(w, info); err != nil {
+		return err
+	}
+
+	for _, child := range e.children {
+		fmt.Fprint(w, ",\n")
+
+		if isRoot {
+			child = ncduRootChild(e, child)
+		}
+
+		if err := writeNCDUEntry(w, child, false); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprint(w, "]")
+
+	return nil
+}
+
+// ncduRootChild names the child of the root by its path relative to the root, the roots
+// gathered under their common parent may lie deeper than right within it.
+func ncduRootChild(root, child *entry) *entry {
+	if child.path == "" {
+		return child
+	}
+
+	rel, err := filepath.Rel(absPath(root.path), absPath(child.path))
+	if err != nil || rel == child.name {
+		return child
+	}
+
+	renamed := *child
+	renamed.name = rel
+
+	return &renamed
+}
+
+func writeNCDUInfo(w io.Writer, info ncduEntry) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+
+	return err
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Parquet constants, see https://github.com/apache/parquet-format/blob/master/src/main/thrift/parquet.thrift
+const (
+	parquetMagic = "PAR1"
+
+	parquetTypeInt32     = 1
+	parquetTypeInt64     = 2
+	parquetTypeByteArray = 6
+
+	parquetRepetitionRequired = 0
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMillis = 9
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetCodecUncompressed = 0
+	parquetPageTypeData      = 0
+)
+
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32 // -1 if none
+	values    bytes.Buffer
+}
+
+// parquetReporter collects entries exceeding the size threshold and writes them as a
+// single row group Parquet file once the scan is over. All columns are required and
+// stored uncompressed with plain encoding.
+type parquetReporter struct {
+	w       io.Writer
+	numRows int64
+
+	path, size, typ, depth, mtime *parquetColumn
+}
+
+func newParquetReporter(w io.Writer) *parquetReporter {
+	return &parquetReporter{
+		w:     w,
+		path:  &parquetColumn{name: "path", typ: parquetTypeByteArray, converted: parquetConvertedUTF8},
+		size:  &parquetColumn{name: "size", typ: parquetTypeInt64, converted: -1},
+		typ:   &parquetColumn{name: "type", typ: parquetTypeByteArray, converted: parquetConvertedUTF8},
+		depth: &parquetColumn{name: "depth", typ: parquetTypeInt32, converted: -1},
+		mtime: &parquetColumn{name: "mtime", typ: parquetTypeInt64, converted: parquetConvertedTimestampMillis},
+	}
+}
+
+func (r *parquetReporter) columns() []*parquetColumn {
+	return []*parquetColumn{r.path, r.size, r.typ, r.depth, r.mtime}
+}
+
+func (r *parquetReporter) report(e *entry) {
+	r.path.appendString(e.path)
+	r.size.appendInt64(e.size)
+	r.typ.appendString(e.typ.String())
+	r.depth.appendInt32(int32(e.depth))
+	r.mtime.appendInt64(e.mtime.UnixMilli())
+	r.numRows++
+}
+
+func (r *parquetReporter) dirDone(dir *entry) {}
+
+func (r *parquetReporter) finish(root *entry) error {
+	bw := bufio.NewWriter(r.w)
+	cw := &countingWriter{w: bw}
+
+	io.WriteString(cw, parquetMagic)
+
+	meta := &thriftWriter{}
+	meta.i32(1, 1) // version
+	meta.listBegin(2, thriftStruct, len(r.columns())+1)
+
+	meta.structBegin()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(r.columns())))
+	meta.structEnd()
+
+	for _, c := range r.columns() {
+		meta.structBegin()
+		meta.i32(1, c.typ)
+		meta.i32(3, parquetRepetitionRequired)
+		meta.binary(4, c.name)
+		if c.converted >= 0 {
+			meta.i32(6, c.converted)
+		}
+		meta.structEnd()
+	}
+
+	meta.i64(3, r.numRows)
+	meta.listBegin(4, thriftStruct, 1)
+	meta.structBegin()
+	meta.listBegin(1, thriftStruct, len(r.columns()))
+
+	totalSize := int64(0)
+
+	for _, c := range r.columns() {
+		offset := cw.n
+
+		page := &thriftWriter{}
+		page.i32(1, parquetPageTypeData)
+		page.i32(2, int32(c.values.Len()))
+		page.i32(3, int32(c.values.Len()))
+		page.fieldStructBegin(5)
+		page.i32(1, int32(r.numRows))
+		page.i32(2, parquetEncodingPlain)
+		page.i32(3, parquetEncodingRLE)
+		page.i32(4, parquetEncodingRLE)
+		page.structEnd()
+		page.stop()
+
+		cw.Write(page.buf.Bytes())
+		cw.Write(c.values.Bytes())
+
+		chunkSize := cw.n - offset
+		totalSize += chunkSize
+
+		meta.structBegin()
+		meta.i64(2, offset)
+		meta.fieldStructBegin(3)
+		meta.i32(1, c.typ)
+		meta.listBegin(2, thriftI32, 2)
+		meta.varint(parquetEncodingPlain)
+		meta.varint(parquetEncodingRLE)
+		meta.listBegin(3, thriftBinary, 1)
+		meta.rawBinary(c.name)
+		meta.i32(4, parquetCodecUncompressed)
+		meta.i64(5, r.numRows)
+		meta.i64(6, chunkSize)
+		meta.i64(7, chunkSize)
+		meta.i64(9, offset)
+		meta.structEnd()
+		meta.structEnd()
+	}
+
+	meta.i64(2, totalSize)
+	meta.i64(3, r.numRows)
+	meta.structEnd()
+	meta.binary(6, progName)
+	meta.stop()
+
+	cw.Write(meta.buf.Bytes())
+	binary.Write(cw, binary.LittleEndian, uint32(meta.buf.Len()))
+	io.WriteString(cw, parquetMagic)
+
+	if cw.err != nil {
+		return cw.err
+	}
+
+	return bw.Flush()
+}
+
+func (c *parquetColumn) appendString(s string) {
+	binary.Write(&c.values, binary.LittleEndian, uint32(len(s)))
+	c.values.WriteString(s)
+}
+
+func (c *parquetColumn) appendInt32(v int32) {
+	binary.Write(&c.values, binary.LittleEndian, v)
+}
+
+func (c *parquetColumn) appendInt64(v int64) {
+	binary.Write(&c.values, binary.LittleEndian, v)
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+
+	return n, err
+}
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter is a minimal encoder of the Thrift compact protocol, sufficient for
+// writing Parquet metadata.
+type thriftWriter struct {
+	buf     bytes.Buffer
+	lastIDs []int16
+	lastID  int16
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	if delta := id - t.lastID; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+
+	t.lastID = id
+}
+
+func (t *thriftWriter) varint(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) rawBinary(s string) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf.Write(b[:binary.PutUvarint(b[:], uint64(len(s)))])
+	t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.rawBinary(s)
+}
+
+func (t *thriftWriter) listBegin(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+
+		var b [binary.MaxVarintLen64]byte
+		t.buf.Write(b[:binary.PutUvarint(b[:], uint64(size))])
+	}
+}
+
+// structBegin starts a struct that is an element of a list.
+func (t *thriftWriter) structBegin() {
+	t.lastIDs = append(t.lastIDs, t.lastID)
+	t.lastID = 0
+}
+
+// fieldStructBegin starts a struct that is a field of the current struct.
+func (t *thriftWriter) fieldStructBegin(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.structBegin()
+}
+
+func (t *thriftWriter) structEnd() {
+	t.stop()
+	t.lastID = t.lastIDs[len(t.lastIDs)-1]
+	t.lastIDs = t.lastIDs[:len(t.lastIDs)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
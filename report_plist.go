@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const plistHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+`
+
+// writePlist renders the whole scanned tree as an Apple XML property list. Every entry is
+// a dict with the same keys as in the JSON output.
+func writePlist(w io.Writer, root *entry) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprint(bw, plistHeader)
+	writePlistEntry(bw, root, 0)
+	fmt.Fprintln(bw, "</plist>")
+
+	return bw.Flush()
+}
+
+func writePlistEntry(w io.Writer, e *entry, depth int) {
+	indent := strings.Repeat("\t", depth)
+
+	fmt.Fprintf(w, "%s<dict>\n", indent)
+	writePlistString(w, indent, "path", e.path)
+	writePlistString(w, indent, "name", e.name)
+	writePlistString(w, indent, "type", e.typ.String())
+	fmt.Fprintf(w, "%s\t<key>size</key>\n%s\t<integer>%d</integer>\n", indent, indent, e.size)
+
+	if len(e.children) > 0 {
+		fmt.Fprintf(w, "%s\t<key>children</key>\n%s\t<array>\n", indent, indent)
+
+		for _, child := range e.children {
+			writePlistEntry(w, child, depth+2)
+		}
+
+		fmt.Fprintf(w, "%s\t</array>\n", indent)
+	}
+
+	fmt.Fprintf(w, "%s</dict>\n", indent)
+}
+
+func writePlistString(w io.Writer, indent, key, value string) {
+	fmt.Fprintf(w, "%s\t<key>%s</key>\n%s\t<string>", indent, key, indent)
+	xml.EscapeText(w, []byte(value))
+	fmt.Fprintln(w, "</string>")
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	sortKeySize  = "size"
+	sortKeyName  = "name"
+	sortKeyMtime = "mtime"
+)
+
+// sortableFormats print every entry on a line of its own, so that the entries can be
+// printed in any order.
+var sortableFormats = map[string]bool{
+	formatText:     true,
+	formatCSV:      true,
+	formatTSV:      true,
+	formatNDJSON:   true,
+	formatDU:       true,
+	formatTemplate: true,
+}
+
+// sortingReporter holds the entries back until the scan is over and passes them on
+// sorted: the largest, the newest or the first by path first, or the other way round if
+// reverse is set.
+type sortingReporter struct {
+	to      reporter
+	by      string
+	reverse bool
+	entries []*entry
+}
+
+func newSortingReporter(to reporter, by string, reverse bool) (*sortingReporter, error) {
+	switch by {
+	case sortKeySize, sortKeyName, sortKeyMtime:
+	default:
+		return nil, fmt.Errorf(
+			"invalid sort order '%v', expected one of: %v", by, strings.Join([]string{sortKeySize, sortKeyName, sortKeyMtime}, ", "),
+		)
+	}
+
+	return &sortingReporter{to: to, by: by, reverse: reverse}, nil
+}
+
+func (r *sortingReporter) report(e *entry) {
+	r.entries = append(r.entries, e)
+}
+
+// dirDone is passed on as it comes, the wrapped reporter is not told about the entries
+// within the directory until the scan is over and must not group them by directories.
+func (r *sortingReporter) dirDone(dir *entry) {
+	r.to.dirDone(dir)
+}
+
+func (r *sortingReporter) finish(root *entry) error {
+	sort.SliceStable(r.entries, func(i, j int) bool {
+		if r.reverse {
+			i, j = j, i
+		}
+
+		a, b := r.entries[i], r.entries[j]
+		switch r.by {
+		case sortKeyName:
+			return a.path < b.path
+		case sortKeyMtime:
+			return a.mtime.After(b.mtime)
+		}
+
+		return a.size > b.size
+	})
+
+	for _, e := range r.entries {
+		r.to.report(e)
+	}
+	r.entries = nil
+
+	return r.to.finish(root)
+}
+
+func (r *sortingReporter) needsTree() bool {
+	t, ok := r.to.(treeNeeder)
+	return ok && t.needsTree()
+}
+
+// needsMtime tells that directories have to be stat-ed to sort them by their mtimes.
+func (r *sortingReporter) needsMtime() bool {
+	if m, ok := r.to.(mtimeNeeder); ok && r.by != sortKeyMtime {
+		return m.needsMtime()
+	}
+
+	return true
+}
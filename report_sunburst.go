@@ -0,0 +1,139 @@
+package main
+
+import (
+	"html/template"
+	"io"
+)
+
+type sunburstNode struct {
+	Name     string          `json:"name"`
+	Path     string          `json:"path"`
+	Size     int64           `json:"size"`
+	Human    string          `json:"human"`
+	Children []*sunburstNode `json:"children,omitempty"`
+}
+
+func newSunburstNode(e *entry, d display) *sunburstNode {
+	n := &sunburstNode{
+		Name:  e.name,
+		Path:  e.path,
+		Size:  e.size,
+		Human: d.size(e.size),
+	}
+
+	for _, child := range e.children {
+		if child.shown {
+			n.Children = append(n.Children, newSunburstNode(child, d))
+		}
+	}
+
+	return n
+}
+
+// writeSunburst renders entries exceeding the size threshold as a standalone HTML page
+// with an interactive sunburst chart. Contents of a directory that do not exceed the
+// threshold are left as a gap in the ring of the directory.
+func writeSunburst(w io.Writer, root *entry, d display) error {
+	node := newSunburstNode(root, d)
+	node.Name = root.path
+
+	return sunburstTemplate.Execute(w, node)
+}
+
+var sunburstTemplate = template.Must(template.New("sunburst").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>space_visualiser sunburst</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+path { stroke: #fff; stroke-width: 1; cursor: pointer; }
+path:hover { opacity: 0.8; }
+circle { cursor: pointer; }
+#info { height: 1.5em; }
+</style>
+</head>
+<body>
+<h1>space_visualiser sunburst</h1>
+<div id="info"></div>
+<svg id="chart" width="800" height="800" viewBox="-400 -400 800 800"></svg>
+<script>
+var data = {{.}};
+var ns = "http://www.w3.org/2000/svg";
+var svg = document.getElementById("chart");
+var info = document.getElementById("info");
+var radius = 400;
+var palette = ["#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f"];
+
+(function link(node, parent) {
+  node.parent = parent;
+  (node.children || []).forEach(function (child) { link(child, node); });
+})(data, null);
+
+function depth(node) {
+  var d = 0;
+  (node.children || []).forEach(function (child) { d = Math.max(d, depth(child) + 1); });
+  return d;
+}
+
+function describe(node) {
+  return node.path + ": " + node.human;
+}
+
+function arc(r0, r1, a0, a1) {
+  if (a1 - a0 >= 2 * Math.PI) { a1 = a0 + 2 * Math.PI - 1e-6; }
+  var large = a1 - a0 > Math.PI ? 1 : 0;
+  function point(r, a) { return (r * Math.sin(a)).toFixed(2) + "," + (-r * Math.cos(a)).toFixed(2); }
+  return "M" + point(r0, a0) + "L" + point(r1, a0) +
+    "A" + r1 + "," + r1 + " 0 " + large + " 1 " + point(r1, a1) +
+    "L" + point(r0, a1) +
+    "A" + r0 + "," + r0 + " 0 " + large + " 0 " + point(r0, a0) + "Z";
+}
+
+function render(root) {
+  while (svg.firstChild) { svg.removeChild(svg.firstChild); }
+  var ring = radius / (depth(root) + 1);
+
+  var center = document.createElementNS(ns, "circle");
+  center.setAttribute("r", ring);
+  center.setAttribute("fill", "#eee");
+  center.addEventListener("click", function () { if (root.parent) { render(root.parent); } });
+  center.addEventListener("mouseover", function () { info.textContent = describe(root); });
+  svg.appendChild(center);
+
+  var label = document.createElementNS(ns, "text");
+  label.setAttribute("text-anchor", "middle");
+  label.setAttribute("pointer-events", "none");
+  label.textContent = root.human;
+  svg.appendChild(label);
+
+  function draw(node, a0, a1, level, color) {
+    if (level > 0) {
+      var path = document.createElementNS(ns, "path");
+      path.setAttribute("d", arc(level * ring, (level + 1) * ring, a0, a1));
+      path.setAttribute("fill", color);
+      path.setAttribute("fill-opacity", Math.max(0.35, 1 - 0.15 * (level - 1)));
+      path.addEventListener("click", function () { if (node.children) { render(node); } });
+      path.addEventListener("mouseover", function () { info.textContent = describe(node); });
+      var title = document.createElementNS(ns, "title");
+      title.textContent = describe(node);
+      path.appendChild(title);
+      svg.appendChild(path);
+    }
+    var a = a0;
+    (node.children || []).forEach(function (child, i) {
+      var span = node.size > 0 ? (a1 - a0) * child.size / node.size : 0;
+      draw(child, a, a + span, level + 1, level === 0 ? palette[i % palette.length] : color);
+      a += span;
+    });
+  }
+
+  draw(root, 0, 2 * Math.PI, 0, null);
+  info.textContent = describe(root);
+}
+
+render(data);
+</script>
+</body>
+</html>
+`))
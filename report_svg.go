@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+)
+
+const (
+	svgWidth         = 1200
+	svgHeight        = 800
+	svgHeaderHeight  = 14
+	svgPadding       = 2
+	svgMinRectSize   = 2
+	svgFontSize      = 11
+	svgCharWidth     = 6.5
+	svgLabelPaddingX = 3
+)
+
+var svgPalette = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f",
+	"#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac",
+}
+
+// writeSVGTreemap renders the whole scanned tree as an SVG treemap, where every rectangle
+// is sized by the number of bytes and nested into the rectangle of its parent directory.
+func writeSVGTreemap(w io.Writer, root *entry, d display) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="%d">`+"\n",
+		svgWidth, svgHeight, svgFontSize,
+	)
+
+	writeSVGEntry(bw, root, treemapRect{0, 0, svgWidth, svgHeight}, 0, d)
+
+	fmt.Fprintln(bw, "</svg>")
+
+	return bw.Flush()
+}
+
+func writeSVGEntry(w io.Writer, e *entry, r treemapRect, depth int, d display) {
+	if r.w < svgMinRectSize || r.h < svgMinRectSize || e.size == 0 {
+		return
+	}
+
+	name := e.name
+	if depth == 0 {
+		name = e.path
+	}
+
+	fmt.Fprintf(w,
+		`<g><title>%s: %s</title><rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" stroke="#fff"/>`,
+		html.EscapeString(e.path), d.size(e.size),
+		r.x, r.y, r.w, r.h, svgPalette[depth%len(svgPalette)],
+	)
+
+	if label := name + " " + d.size(e.size); r.h >= svgHeaderHeight {
+		label = truncateSVGLabel(label, r.w-2*svgLabelPaddingX)
+		if label != "" {
+			fmt.Fprintf(w, `<text x="%.1f" y="%.1f" fill="#fff">%s</text>`,
+				r.x+svgLabelPaddingX, r.y+svgFontSize, html.EscapeString(label),
+			)
+		}
+	}
+
+	fmt.Fprintln(w, "</g>")
+
+	if e.typ != entryDir || len(e.children) == 0 {
+		return
+	}
+
+	inner := treemapRect{
+		x: r.x + svgPadding,
+		y: r.y + svgHeaderHeight,
+		w: r.w - 2*svgPadding,
+		h: r.h - svgHeaderHeight - svgPadding,
+	}
+	if inner.w < svgMinRectSize || inner.h < svgMinRectSize {
+		return
+	}
+
+	children := make([]*entry, 0, len(e.children))
+	for _, child := range e.children {
+		if child.size > 0 {
+			children = append(children, child)
+		}
+	}
+
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].size > children[j].size
+	})
+
+	areas := make([]float64, len(children))
+	scale := inner.w * inner.h / float64(e.size)
+
+	for i, child := range children {
+		areas[i] = float64(child.size) * scale
+	}
+
+	for i, rect := range squarify(areas, inner) {
+		writeSVGEntry(w, children[i], rect, depth+1, d)
+	}
+}
+
+func truncateSVGLabel(label string, width float64) string {
+	maxChars := int(width / svgCharWidth)
+
+	runes := []rune(label)
+	if len(runes) <= maxChars {
+		return label
+	}
+
+	if maxChars < 4 {
+		return ""
+	}
+
+	return string(runes[:maxChars-1]) + "…"
+}
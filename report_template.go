@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"text/template"
+	"time"
+)
+
+const templateFooterName = "footer"
+
+// templateEntry is the data passed to the user-supplied template.
+type templateEntry struct {
+	Path      string
+	Name      string
+	Type      string
+	Size      int64
+	HumanSize string
+	Depth     int
+	MTime     time.Time
+	Parent    string
+	Files     int64
+	Dirs      int64
+	Mount     string
+	Shared    int64
+	Target    string
+	Changed   bool
+}
+
+func newTemplateEntry(e *entry, d display) *templateEntry {
+	return &templateEntry{
+		Path:      d.path(e.path),
+		Name:      d.path(e.name),
+		Type:      e.typ.String(),
+		Size:      e.size,
+		HumanSize: d.size(e.size),
+		Depth:     e.depth,
+		MTime:     e.mtime,
+		Parent:    d.path(filepath.Dir(e.path)),
+		Files:     e.files,
+		Dirs:      e.dirs,
+		Mount:     mountString(e),
+		Shared:    e.shared,
+		Target:    d.path(e.target),
+		Changed:   e.changed,
+	}
+}
+
+// templateReporter executes the user-supplied template for every entry exceeding the size
+// threshold, each execution is followed by a newline. If the template defines a template
+// named "footer", it is executed for the root once the scan is over.
+type templateReporter struct {
+	w      io.Writer
+	tmpl   *template.Template
+	footer *template.Template
+
+	display
+}
+
+func newTemplateReporter(w io.Writer, text string, d display) (*templateReporter, error) {
+	tmpl, err := template.New("entry").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template: %v", err)
+	}
+
+	return &templateReporter{
+		w:       w,
+		tmpl:    tmpl,
+		footer:  tmpl.Lookup(templateFooterName),
+		display: d,
+	}, nil
+}
+
+func (r *templateReporter) report(e *entry) {
+	if err := r.tmpl.Execute(r.w, newTemplateEntry(e, r.display)); err != nil {
+		log.Printf("error: could not execute template for %v: %v", e.path, err)
+		return
+	}
+
+	fmt.Fprintln(r.w)
+}
+
+func (r *templateReporter) dirDone(dir *entry) {}
+
+func (r *templateReporter) finish(root *entry) error {
+	if r.footer == nil {
+		return nil
+	}
+
+	if err := r.footer.Execute(r.w, newTemplateEntry(root, r.display)); err != nil {
+		return fmt.Errorf("could not execute footer template: %v", err)
+	}
+
+	_, err := fmt.Fprintln(r.w)
+
+	return err
+}
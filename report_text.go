@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// textReporter prints entries as soon as they are found. Files of one directory are
+// grouped together with empty lines around them.
+type textReporter struct {
+	w         io.Writer
+	colors    *colorizer
+	otherSize string
+	bothSizes bool
+	counts    bool
+	diskUsage bool
+	shared    bool
+	mtimes    bool
+	atimes    bool
+	modes     bool
+	flat      bool
+	dirs      map[*entry]*textDirState
+
+	display
+}
+
+type textDirState struct {
+	filesPrinted               int
+	shouldPrintAClosingNewLine bool
+}
+
+func newTextReporter(w io.Writer, opts reportOptions) *textReporter {
+	return &textReporter{
+		w:         w,
+		colors:    opts.colors,
+		otherSize: opts.otherSize,
+		bothSizes: opts.bothSizes,
+		counts:    opts.counts,
+		diskUsage: opts.diskUsage,
+		shared:    opts.shared,
+		mtimes:    opts.mtimes,
+		atimes:    opts.atimes,
+		modes:     opts.modes,
+		flat:      opts.flat,
+		dirs:      make(map[*entry]*textDirState),
+		display:   opts.display,
+	}
+}
+
+func (r *textReporter) state(dir *entry) *textDirState {
+	s, ok := r.dirs[dir]
+	if !ok {
+		s = &textDirState{}
+		r.dirs[dir] = s
+	}
+
+	return s
+}
+
+func (r *textReporter) report(e *entry) {
+	if r.flat {
+		r.printEntry(e)
+		return
+	}
+
+	if e.parent == nil {
+		r.printEntry(e)
+		fmt.Fprintln(r.w)
+
+		return
+	}
+
+	s := r.state(e.parent)
+
+	if e.typ == entryFile && s.filesPrinted == 0 {
+		// create an empty line before a group of files in one directory
+		fmt.Fprintln(r.w)
+	}
+
+	r.printEntry(e)
+
+	if s.shouldPrintAClosingNewLine {
+		// create an empty line after a group of files in one directory
+		fmt.Fprintln(r.w)
+	}
+
+	if e.typ == entryFile {
+		s.filesPrinted++
+	}
+}
+
+func (r *textReporter) printEntry(e *entry) {
+	line := fmt.Sprintf("%v: %v", r.path(e.path), r.size(e.size))
+	switch {
+	case r.otherSize == "":
+	case e.sparse:
+		line += fmt.Sprintf(" (sparse, %v %v)", r.size(e.otherSize), r.otherSize)
+	case r.bothSizes:
+		line += fmt.Sprintf(" (%v %v)", r.size(e.otherSize), r.otherSize)
+	}
+
+	if r.shared && e.shared > 0 {
+		allocated := e.otherSize
+		if r.diskUsage {
+			allocated = e.size
+		}
+
+		line += fmt.Sprintf(" (%v exclusive, %v shared)", r.size(allocated-e.shared), r.size(e.shared))
+	}
+
+	if e.streams > 0 {
+		line += fmt.Sprintf(" (%v in alternate data streams)", r.size(e.streams))
+	}
+
+	if r.mtimes && e.typ == entryFile {
+		line += fmt.Sprintf(" (modified %v)", e.mtime.Format(time.DateOnly))
+	}
+	if r.atimes && e.typ == entryFile && !e.atime.IsZero() {
+		line += fmt.Sprintf(" (accessed %v)", e.atime.Format(time.DateOnly))
+	}
+
+	if r.modes && e.typ == entryFile {
+		line += " (" + lsMode(e.mode) + ")"
+	}
+
+	if e.changed {
+		line += " (possibly stale)"
+	}
+
+	if e.target != "" {
+		line += " -> " + r.path(e.target)
+	}
+
+	if e.mount != nil {
+		line += fmt.Sprintf(" [%v]", e.mount)
+	}
+
+	if r.counts && e.typ == entryDir {
+		line += fmt.Sprintf(" in %v files and %v dirs", humanize.Comma(e.files), humanize.Comma(e.dirs))
+	}
+
+	fmt.Fprintln(r.w, r.colors.paint(e.size, line))
+}
+
+func (r *textReporter) dirDone(dir *entry) {
+	s, ok := r.dirs[dir]
+	if !ok {
+		return
+	}
+
+	delete(r.dirs, dir)
+
+	if s.filesPrinted > 0 && dir.parent != nil {
+		r.state(dir.parent).shouldPrintAClosingNewLine = true
+	}
+}
+
+func (r *textReporter) finish(root *entry) error {
+	return nil
+}
+
+func (r *textReporter) needsMtime() bool { return false }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// writeTree prints entries exceeding the size threshold nested under their parent
+// directories using box-drawing characters.
+func writeTree(w io.Writer, root *entry, colors *colorizer, d display) error {
+	if !root.shown {
+		return nil
+	}
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, colors.paint(root.size, fmt.Sprintf("%v: %v", d.path(root.path), d.size(root.size))))
+	writeTreeChildren(bw, root, "", colors, d)
+
+	return bw.Flush()
+}
+
+func writeTreeChildren(w io.Writer, dir *entry, indent string, colors *colorizer, d display) {
+	var children []*entry
+	for _, child := range dir.children {
+		if child.shown {
+			children = append(children, child)
+		}
+	}
+
+	for i, child := range children {
+		branch, childIndent := "├── ", indent+"│   "
+		if i == len(children)-1 {
+			branch, childIndent = "└── ", indent+"    "
+		}
+
+		fmt.Fprintln(w, indent+branch+colors.paint(child.size, fmt.Sprintf("%v: %v", d.path(child.name), d.size(child.size))))
+
+		if child.typ == entryDir {
+			writeTreeChildren(w, child, childIndent, colors, d)
+		}
+	}
+}
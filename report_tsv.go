@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var tsvColumns = map[string]func(e *entry, d display) string{
+	"path":       func(e *entry, d display) string { return tsvPath(e.path, d) },
+	"name":       func(e *entry, d display) string { return tsvPath(e.name, d) },
+	"type":       func(e *entry, d display) string { return e.typ.String() },
+	"size":       func(e *entry, d display) string { return strconv.FormatInt(e.size, 10) },
+	"human_size": func(e *entry, d display) string { return d.size(e.size) },
+	"mtime":      func(e *entry, d display) string { return e.mtime.Format(time.RFC3339) },
+	"depth":      func(e *entry, d display) string { return strconv.Itoa(e.depth) },
+	"parent":     func(e *entry, d display) string { return tsvPath(filepath.Dir(e.path), d) },
+	"files":      func(e *entry, d display) string { return strconv.FormatInt(e.files, 10) },
+	"dirs":       func(e *entry, d display) string { return strconv.FormatInt(e.dirs, 10) },
+	"mount":      func(e *entry, d display) string { return tsvEscaper.Replace(mountString(e)) },
+	"shared":     func(e *entry, d display) string { return strconv.FormatInt(e.shared, 10) },
+	"target":     func(e *entry, d display) string { return tsvPath(e.target, d) },
+	"changed":    func(e *entry, d display) string { return strconv.FormatBool(e.changed) },
+}
+
+var tsvColumnNames = []string{
+	"path", "name", "type", "size", "human_size", "mtime", "depth", "parent", "files", "dirs",
+	"mount", "shared", "target", "changed",
+}
+
+// tsvReporter prints one tab-separated line per entry exceeding the size threshold with
+// the columns chosen by user. Tabs, newlines and backslashes in values are escaped.
+type tsvReporter struct {
+	w       io.Writer
+	columns []func(e *entry, d display) string
+
+	display
+}
+
+func newTSVReporter(w io.Writer, columns string, d display) (*tsvReporter, error) {
+	r := &tsvReporter{
+		w:       w,
+		display: d,
+	}
+
+	for _, name := range strings.Split(columns, ",") {
+		column, ok := tsvColumns[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown column '%v'", name)
+		}
+
+		r.columns = append(r.columns, column)
+	}
+
+	return r, nil
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// tsvPath renders the path for a column, escaped paths have no tabs or newlines left, so
+// only raw ones are escaped with tsvEscaper.
+func tsvPath(p string, d display) string {
+	if d.raw {
+		return tsvEscaper.Replace(d.path(p))
+	}
+
+	return d.path(p)
+}
+
+func (r *tsvReporter) report(e *entry) {
+	values := make([]string, len(r.columns))
+	for i, column := range r.columns {
+		values[i] = column(e, r.display)
+	}
+
+	fmt.Fprintln(r.w, strings.Join(values, "\t"))
+}
+
+func (r *tsvReporter) dirDone(dir *entry) {}
+
+func (r *tsvReporter) finish(root *entry) error {
+	return nil
+}
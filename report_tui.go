@@ -0,0 +1,636 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	tuiBarWidth     = 20
+	tuiHeaderLines  = 2
+	tuiFooterLines  = 1
+	tuiDefaultWidth = 80
+	tuiDefaultRows  = 24
+)
+
+// ANSI escape sequences used to control the terminal.
+const (
+	ansiAltScreenOn  = "\x1b[?1049h"
+	ansiAltScreenOff = "\x1b[?1049l"
+	ansiCursorHide   = "\x1b[?25l"
+	ansiCursorShow   = "\x1b[?25h"
+	ansiHome         = "\x1b[H"
+	ansiClearScreen  = "\x1b[2J"
+	ansiClearLine    = "\x1b[K"
+	ansiReverse      = "\x1b[7m"
+	ansiReset        = "\x1b[0m"
+)
+
+type tuiKey int
+
+const (
+	keyUnknown tuiKey = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyEnter
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyMark
+	keyDelete
+	keyYes
+	keySortSize
+	keySortName
+	keySortMtime
+	keySortCount
+	keySearch
+	keyQuit
+)
+
+type tuiSort int
+
+const (
+	sortBySize tuiSort = iota
+	sortByName
+	sortByMtime
+	sortByCount
+)
+
+var tuiSortNames = map[tuiSort]string{
+	sortBySize:  "size",
+	sortByName:  "name",
+	sortByMtime: "mtime",
+	sortByCount: "items",
+}
+
+// tui is an interactive full-screen browser of the scanned tree.
+type tui struct {
+	in  *os.File
+	out *bufio.Writer
+
+	dir     *entry
+	items   []*entry
+	cursor  int
+	offset  int
+	width   int
+	height  int
+	message string
+
+	// marked are the entries selected for deletion.
+	marked map[*entry]bool
+	// confirm holds the entries waiting for the deletion to be confirmed.
+	confirm []*entry
+
+	sortBy   tuiSort
+	sortDesc bool
+	// counts caches the number of entries in subtrees.
+	counts map[*entry]int
+
+	// searching is set while user types the filter.
+	searching bool
+	filter    string
+	filterRe  *regexp.Regexp
+
+	display
+}
+
+// runTUI opens the interactive browser of the scanned tree. It talks to the terminal
+// directly, so the output file is ignored.
+func runTUI(root *entry, d display) error {
+	if !isTerminal(os.Stdin.Fd()) || !isTerminal(os.Stdout.Fd()) {
+		return errors.New("interactive mode requires a terminal")
+	}
+
+	restore, err := makeRaw(os.Stdin.Fd())
+	if err != nil {
+		return fmt.Errorf("could not switch terminal to raw mode: %v", err)
+	}
+	defer restore()
+
+	t := &tui{
+		in:       os.Stdin,
+		out:      bufio.NewWriter(os.Stdout),
+		marked:   make(map[*entry]bool),
+		sortDesc: true,
+		counts:   make(map[*entry]int),
+		display:  d,
+	}
+
+	t.out.WriteString(ansiAltScreenOn + ansiCursorHide)
+	defer func() {
+		t.out.WriteString(ansiCursorShow + ansiAltScreenOff)
+		t.out.Flush()
+	}()
+
+	t.open(root, nil)
+
+	for {
+		t.render()
+
+		input, err := t.readInput()
+		if err != nil {
+			return err
+		}
+
+		if t.searching {
+			t.handleSearchInput(input)
+			continue
+		}
+
+		key := keyFor(input)
+
+		if t.confirm != nil {
+			t.handleConfirm(key == keyYes)
+			continue
+		}
+
+		if key == keyQuit {
+			return nil
+		}
+
+		t.handle(key)
+	}
+}
+
+// open makes dir the current directory, placing the cursor at selected if it is given.
+func (t *tui) open(dir *entry, selected *entry) {
+	t.dir = dir
+	t.offset = 0
+	t.message = ""
+	t.setFilter("")
+
+	t.refresh(selected)
+}
+
+// refresh rebuilds the list of items of the current directory, placing the cursor at
+// selected if it is still there.
+func (t *tui) refresh(selected *entry) {
+	t.items = t.items[:0]
+	for _, child := range t.dir.children {
+		if t.matches(child) {
+			t.items = append(t.items, child)
+		}
+	}
+
+	t.sortItems()
+
+	t.cursor = 0
+	for i, item := range t.items {
+		if item == selected {
+			t.cursor = i
+		}
+	}
+}
+
+// setFilter makes only entries matching the filter visible. The filter is treated as a
+// regexp, if it is not a valid one, names are matched by substring.
+func (t *tui) setFilter(filter string) {
+	t.filter = filter
+	t.filterRe = nil
+
+	if re, err := regexp.Compile(filter); err == nil {
+		t.filterRe = re
+	}
+}
+
+func (t *tui) matches(e *entry) bool {
+	if t.filter == "" {
+		return true
+	}
+
+	if t.filterRe != nil {
+		return t.filterRe.MatchString(e.name)
+	}
+
+	return strings.Contains(e.name, t.filter)
+}
+
+func (t *tui) handleSearchInput(input string) {
+	switch {
+	case input == "\r" || input == "\n":
+		t.searching = false
+	case input == "\x1b" || input == "\x03":
+		t.searching = false
+		t.setFilter("")
+	case input == "\x7f" || input == "\b":
+		if runes := []rune(t.filter); len(runes) > 0 {
+			t.setFilter(string(runes[:len(runes)-1]))
+		}
+	case !strings.HasPrefix(input, "\x1b") && utf8.ValidString(input) && input >= " ":
+		t.setFilter(t.filter + input)
+	default:
+		return
+	}
+
+	t.refresh(t.selected())
+}
+
+func (t *tui) sortItems() {
+	less := func(a, b *entry) bool {
+		switch t.sortBy {
+		case sortByName:
+			return a.name < b.name
+		case sortByMtime:
+			return a.mtime.Before(b.mtime)
+		case sortByCount:
+			return t.count(a) < t.count(b)
+		default:
+			return a.size < b.size
+		}
+	}
+
+	sort.SliceStable(t.items, func(i, j int) bool {
+		if t.sortDesc {
+			return less(t.items[j], t.items[i])
+		}
+
+		return less(t.items[i], t.items[j])
+	})
+}
+
+// toggleSort sorts items by the given key, choosing the key that is already used
+// reverses the order.
+func (t *tui) toggleSort(by tuiSort) {
+	if t.sortBy == by {
+		t.sortDesc = !t.sortDesc
+	} else {
+		t.sortBy = by
+		// names are expected to go in alphabetical order, the rest from the largest
+		t.sortDesc = by != sortByName
+	}
+
+	t.refresh(t.selected())
+}
+
+// count returns the number of entries in the subtree of e including e itself.
+func (t *tui) count(e *entry) int {
+	if n, ok := t.counts[e]; ok {
+		return n
+	}
+
+	n := 1
+	for _, child := range e.children {
+		n += t.count(child)
+	}
+
+	t.counts[e] = n
+
+	return n
+}
+
+func (t *tui) selected() *entry {
+	if t.cursor < 0 || t.cursor >= len(t.items) {
+		return nil
+	}
+
+	return t.items[t.cursor]
+}
+
+func (t *tui) handle(key tuiKey) {
+	page := t.listHeight()
+
+	switch key {
+	case keyUp:
+		t.cursor--
+	case keyDown:
+		t.cursor++
+	case keyPageUp:
+		t.cursor -= page
+	case keyPageDown:
+		t.cursor += page
+	case keyHome:
+		t.cursor = 0
+	case keyEnd:
+		t.cursor = len(t.items) - 1
+	case keyRight, keyEnter:
+		if e := t.selected(); e != nil && e.typ == entryDir {
+			t.open(e, nil)
+		}
+	case keyLeft:
+		if t.dir.parent != nil {
+			t.open(t.dir.parent, t.dir)
+		}
+	case keyMark:
+		if e := t.selected(); e != nil {
+			if t.marked[e] {
+				delete(t.marked, e)
+			} else {
+				t.marked[e] = true
+			}
+			t.cursor++
+		}
+	case keyDelete:
+		t.askDelete()
+	case keySortSize:
+		t.toggleSort(sortBySize)
+	case keySortName:
+		t.toggleSort(sortByName)
+	case keySortMtime:
+		t.toggleSort(sortByMtime)
+	case keySortCount:
+		t.toggleSort(sortByCount)
+	case keySearch:
+		t.searching = true
+		t.setFilter("")
+		t.refresh(t.selected())
+	}
+
+	t.cursor = max(0, min(t.cursor, len(t.items)-1))
+}
+
+// askDelete asks user to confirm deletion of the marked entries or, if there are none,
+// of the selected one.
+func (t *tui) askDelete() {
+	var targets []*entry
+
+	for e := range t.marked {
+		targets = append(targets, e)
+	}
+
+	if len(targets) == 0 {
+		if e := t.selected(); e != nil {
+			targets = append(targets, e)
+		}
+	}
+
+	if len(targets) == 0 {
+		return
+	}
+
+	for _, e := range targets {
+		if e.incomplete {
+			t.message = fmt.Sprintf(
+				"cannot delete %s, some of its contents were left out of the scan, they would be deleted unseen", e.path,
+			)
+
+			return
+		}
+	}
+
+	t.confirm = targets
+	t.message = fmt.Sprintf("delete %d entries freeing %s? (y/N)", len(targets), t.size(totalSize(targets)))
+
+	if len(targets) == 1 {
+		t.message = fmt.Sprintf("delete %s freeing %s? (y/N)", targets[0].path, t.size(targets[0].size))
+	}
+}
+
+func (t *tui) handleConfirm(confirmed bool) {
+	targets := t.confirm
+	t.confirm = nil
+	t.message = ""
+
+	if !confirmed {
+		return
+	}
+
+	deleted, freed := 0, int64(0)
+	var failed error
+
+	for _, e := range targets {
+		if err := removeWithinFileSystem(e.path); err != nil {
+			failed = err
+			continue
+		}
+
+		delete(t.marked, e)
+		e.detach()
+
+		// counts of all the ancestors are not valid anymore
+		t.counts = make(map[*entry]int)
+
+		deleted++
+		freed += e.size
+	}
+
+	t.refresh(t.selected())
+
+	t.message = fmt.Sprintf("deleted %d entries, freed %s", deleted, t.size(freed))
+	if failed != nil {
+		t.message += fmt.Sprintf(", error: %v", failed)
+	}
+}
+
+// removeWithinFileSystem removes path and everything within it the way os.RemoveAll does,
+// but does not descend into directories on other file systems mounted within it, as the
+// scan did not count their contents.
+func removeWithinFileSystem(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	id, ok := fileIDOf(info)
+
+	return removeWithinDevice(path, info, id.dev, ok)
+}
+
+// removeWithinDevice removes path if it is on device dev, directories are emptied first.
+// Devices are only compared if checkDev is set.
+func removeWithinDevice(path string, info os.FileInfo, dev uint64, checkDev bool) error {
+	if !info.IsDir() {
+		return os.Remove(path)
+	}
+
+	if id, ok := fileIDOf(info); checkDev && ok && id.dev != dev {
+		return fmt.Errorf("not deleting %v, another file system is mounted on it", path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	var failed error
+	for _, dirEntry := range entries {
+		child := filepath.Join(path, dirEntry.Name())
+
+		info, err := os.Lstat(child)
+		if err == nil {
+			err = removeWithinDevice(child, info, dev, checkDev)
+		}
+		if err != nil && failed == nil {
+			failed = err
+		}
+	}
+	if failed != nil {
+		return failed
+	}
+
+	return os.Remove(path)
+}
+
+func totalSize(entries []*entry) int64 {
+	size := int64(0)
+	for _, e := range entries {
+		size += e.size
+	}
+
+	return size
+}
+
+func (t *tui) readInput() (string, error) {
+	var buf [16]byte
+
+	n, err := t.in.Read(buf[:])
+	if err != nil {
+		return "", err
+	}
+
+	return string(buf[:n]), nil
+}
+
+func keyFor(input string) tuiKey {
+	switch input {
+	case "\x1b[A", "k":
+		return keyUp
+	case "\x1b[B", "j":
+		return keyDown
+	case "\x1b[D", "h", "\x7f", "\b":
+		return keyLeft
+	case "\x1b[C", "l":
+		return keyRight
+	case "\r", "\n":
+		return keyEnter
+	case "\x1b[5~":
+		return keyPageUp
+	case "\x1b[6~":
+		return keyPageDown
+	case "\x1b[H", "\x1b[1~", "g":
+		return keyHome
+	case "\x1b[F", "\x1b[4~", "G":
+		return keyEnd
+	case " ":
+		return keyMark
+	case "d":
+		return keyDelete
+	case "y", "Y":
+		return keyYes
+	case "s":
+		return keySortSize
+	case "n":
+		return keySortName
+	case "m", "M":
+		return keySortMtime
+	case "c", "C":
+		return keySortCount
+	case "/":
+		return keySearch
+	case "q", "\x03":
+		return keyQuit
+	default:
+		return keyUnknown
+	}
+}
+
+func (t *tui) listHeight() int {
+	return max(1, t.height-tuiHeaderLines-tuiFooterLines)
+}
+
+func (t *tui) render() {
+	t.width, t.height = tuiDefaultWidth, tuiDefaultRows
+	if w, h, err := terminalSize(os.Stdout.Fd()); err == nil && w > 0 && h > 0 {
+		t.width, t.height = w, h
+	}
+
+	rows := t.listHeight()
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	}
+	if t.cursor >= t.offset+rows {
+		t.offset = t.cursor - rows + 1
+	}
+
+	t.out.WriteString(ansiHome + ansiClearScreen)
+
+	order := "desc"
+	if !t.sortDesc {
+		order = "asc"
+	}
+
+	t.line(ansiReverse, fmt.Sprintf(" %s  %s  (%d entries, sorted by %s %s)",
+		t.dir.path, t.size(t.dir.size), len(t.items), tuiSortNames[t.sortBy], order,
+	))
+	t.line("", "")
+
+	for i := t.offset; i < t.offset+rows; i++ {
+		if i >= len(t.items) {
+			t.line("", "")
+			continue
+		}
+
+		style := ""
+		if i == t.cursor {
+			style = ansiReverse
+		}
+
+		t.line(style, t.formatItem(t.items[i]))
+	}
+
+	footer := " ↑/↓ move  →/enter open  ←/backspace up  space mark  d delete  s/n/m/c sort  / search  q quit"
+	switch {
+	case t.searching:
+		footer = " /" + t.filter
+	case t.message != "":
+		footer = " " + t.message
+	case t.filter != "":
+		footer = fmt.Sprintf(" filter: %s  (/ then esc to clear)", t.filter)
+	}
+
+	t.out.WriteString(ansiReverse + truncate(footer, t.width) + ansiClearLine + ansiReset)
+	t.out.Flush()
+}
+
+func (t *tui) formatItem(e *entry) string {
+	share := 0.0
+	if t.dir.size > 0 {
+		share = float64(e.size) / float64(t.dir.size)
+	}
+
+	filled := int(share*tuiBarWidth + 0.5)
+	bar := strings.Repeat("#", filled) + strings.Repeat(" ", tuiBarWidth-filled)
+
+	name := e.name
+	if e.typ == entryDir {
+		name += "/"
+	}
+
+	mark := " "
+	if t.marked[e] {
+		mark = "*"
+	}
+
+	return fmt.Sprintf("%s%10s %5.1f%% [%s] %s", mark, t.size(e.size), share*100, bar, name)
+}
+
+func (t *tui) line(style, s string) {
+	t.out.WriteString(style + truncate(s, t.width) + ansiClearLine + ansiReset + "\r\n")
+}
+
+// truncate cuts s so that it fits into width runes.
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+
+	if width <= 1 {
+		return string(runes[:max(0, width)])
+	}
+
+	return string(runes[:width-1]) + "…"
+}
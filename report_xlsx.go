@@ -0,0 +1,155 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+const (
+	// unixEpochExcelSerial is the Excel serial date of 1970-01-01
+	unixEpochExcelSerial = 25569
+	secondsInDay         = 24 * 60 * 60
+)
+
+// xlsxReporter collects entries exceeding the size threshold and writes them as an Excel
+// workbook with separate sheets for directories and files once the scan is over.
+type xlsxReporter struct {
+	w     io.Writer
+	dirs  []*entry
+	files []*entry
+
+	display
+}
+
+func newXLSXReporter(w io.Writer, d display) *xlsxReporter {
+	return &xlsxReporter{
+		w:       w,
+		display: d,
+	}
+}
+
+func (r *xlsxReporter) report(e *entry) {
+	if e.typ == entryDir {
+		r.dirs = append(r.dirs, e)
+	} else {
+		r.files = append(r.files, e)
+	}
+}
+
+func (r *xlsxReporter) dirDone(dir *entry) {}
+
+func (r *xlsxReporter) finish(root *entry) error {
+	zw := zip.NewWriter(r.w)
+
+	parts := []struct {
+		name    string
+		content []byte
+	}{
+		{"[Content_Types].xml", []byte(xlsxContentTypes)},
+		{"_rels/.rels", []byte(xlsxRels)},
+		{"xl/workbook.xml", []byte(xlsxWorkbook)},
+		{"xl/_rels/workbook.xml.rels", []byte(xlsxWorkbookRels)},
+		{"xl/styles.xml", []byte(xlsxStyles)},
+		{"xl/worksheets/sheet1.xml", xlsxSheet(r.dirs, r.display)},
+		{"xl/worksheets/sheet2.xml", xlsxSheet(r.files, r.display)},
+	}
+
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+
+		if _, err := f.Write(part.content); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func xlsxSheet(entries []*entry, d display) []byte {
+	var b bytes.Buffer
+
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<cols><col min="1" max="1" width="80" customWidth="1"/>` +
+		`<col min="2" max="4" width="18" customWidth="1"/></cols>`)
+	b.WriteString(`<sheetData>`)
+	b.WriteString(`<row r="1">`)
+
+	for i, header := range []string{"Path", "Size (bytes)", "Size", "Modified"} {
+		xlsxStringCell(&b, i, 1, header, 1)
+	}
+
+	b.WriteString(`</row>`)
+
+	for i, e := range entries {
+		row := i + 2
+
+		fmt.Fprintf(&b, `<row r="%d">`, row)
+		xlsxStringCell(&b, 0, row, e.path, 0)
+		fmt.Fprintf(&b, `<c r="B%d"><v>%d</v></c>`, row, e.size)
+		xlsxStringCell(&b, 2, row, d.size(e.size), 0)
+		fmt.Fprintf(&b, `<c r="D%d" s="2"><v>%f</v></c>`,
+			row, float64(e.mtime.Unix())/secondsInDay+unixEpochExcelSerial,
+		)
+		b.WriteString(`</row>`)
+	}
+
+	b.WriteString(`</sheetData>`)
+	fmt.Fprintf(&b, `<autoFilter ref="A1:D%d"/>`, len(entries)+1)
+	b.WriteString(`</worksheet>`)
+
+	return b.Bytes()
+}
+
+func xlsxStringCell(b *bytes.Buffer, column, row int, value string, style int) {
+	fmt.Fprintf(b, `<c r="%c%d" t="inlineStr" s="%d"><is><t>`, 'A'+column, row, style)
+	xml.EscapeText(b, []byte(value))
+	b.WriteString(`</t></is></c>`)
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet2.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const xlsxRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets>` +
+	`<sheet name="Directories" sheetId="1" r:id="rId1"/>` +
+	`<sheet name="Files" sheetId="2" r:id="rId2"/>` +
+	`</sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>` +
+	`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// xlsxStyles defines three cell formats: 0 is the default one, 1 is bold for headers
+// and 2 is a date and time.
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="22" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`</styleSheet>`
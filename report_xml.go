@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// The XML document produced by writeXML has the following schema:
+//
+//	<scan root="/scanned/dir" generated="2006-01-02T15:04:05Z07:00">
+//	  <dir path="/scanned/dir" name="dir" size="12345">
+//	    <file path="/scanned/dir/file" name="file" size="345"/>
+//	    <dir path="/scanned/dir/sub" name="sub" size="12000">
+//	      ...
+//	    </dir>
+//	  </dir>
+//	</scan>
+//
+// scan is the root element, it contains exactly one dir element describing the scanned
+// directory. Every dir element contains dir and file elements for its contents. size is
+// always in bytes and includes the whole contents for directories.
+type xmlScan struct {
+	XMLName   xml.Name  `xml:"scan"`
+	Root      string    `xml:"root,attr"`
+	Generated string    `xml:"generated,attr"`
+	Tree      *xmlEntry `xml:"dir"`
+}
+
+type xmlEntry struct {
+	XMLName  xml.Name
+	Path     string      `xml:"path,attr"`
+	Name     string      `xml:"name,attr"`
+	Size     int64       `xml:"size,attr"`
+	Children []*xmlEntry `xml:",any"`
+}
+
+func newXMLEntry(e *entry) *xmlEntry {
+	xe := &xmlEntry{
+		XMLName: xml.Name{Local: e.typ.String()},
+		Path:    e.path,
+		Name:    e.name,
+		Size:    e.size,
+	}
+
+	for _, child := range e.children {
+		xe.Children = append(xe.Children, newXMLEntry(child))
+	}
+
+	return xe
+}
+
+// writeXML renders the whole scanned tree as an XML document.
+func writeXML(w io.Writer, root *entry) error {
+	scan := &xmlScan{
+		Root:      root.path,
+		Generated: time.Now().Format(time.RFC3339),
+		Tree:      newXMLEntry(root),
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(scan); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
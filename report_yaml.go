@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writeYAML renders the whole scanned tree as a YAML document. The structure is the same
+// as the one produced by writeJSON, so any field added to jsonEntry must be added here too.
+func writeYAML(w io.Writer, root *entry) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "---")
+	writeYAMLEntry(bw, newJSONEntry(root), 0)
+
+	return bw.Flush()
+}
+
+func writeYAMLEntry(w io.Writer, je *jsonEntry, indent int) {
+	// the first key of a list item shares the line with the dash
+	prefix := strings.Repeat("  ", indent)
+	first := prefix
+	if indent > 0 {
+		first = strings.Repeat("  ", indent-1) + "- "
+	}
+
+	fmt.Fprintf(w, "%spath: %s\n", first, strconv.Quote(je.Path))
+	fmt.Fprintf(w, "%sname: %s\n", prefix, strconv.Quote(je.Name))
+	fmt.Fprintf(w, "%stype: %s\n", prefix, je.Type)
+	fmt.Fprintf(w, "%ssize: %d\n", prefix, je.Size)
+
+	if len(je.Children) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%schildren:\n", prefix)
+
+	for _, child := range je.Children {
+		writeYAMLEntry(w, child, indent+1)
+	}
+}
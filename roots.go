@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// dropNestedRoots leaves out the roots lying within the roots given before them, they
+// are scanned and reported as parts of those.
+func dropNestedRoots(dirs []string) []string {
+	var kept, keptAbs []string
+
+next:
+	for _, dir := range dirs {
+		abs := absPath(dir)
+		for i, prev := range keptAbs {
+			if isWithin(abs, prev) {
+				log.Printf("warning: skipping %v, it lies within %v given before", dir, kept[i])
+				continue next
+			}
+		}
+
+		kept, keptAbs = append(kept, dir), append(keptAbs, abs)
+	}
+
+	return kept
+}
+
+// rootsOverlap tells whether a root lies within another one. Overlapping roots are
+// scanned one after another, so that the shared directories are always counted in the
+// same root whatever the number of jobs.
+func rootsOverlap(dirs []string) bool {
+	for i, dir := range dirs {
+		for j, other := range dirs {
+			if i != j && isWithin(absPath(dir), absPath(other)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+
+	return path
+}
+
+// commonParent returns the innermost directory holding all the paths, it is relative to
+// the working directory if all of them are.
+func commonParent(cwd string, paths []string) string {
+	relative := true
+	for _, path := range paths {
+		relative = relative && !filepath.IsAbs(path)
+	}
+
+	parent := filepath.Dir(resolvePath(cwd, paths[0]))
+	for _, path := range paths[1:] {
+		path = resolvePath(cwd, path)
+
+		for !isWithin(path, parent) {
+			up := filepath.Dir(parent)
+			if up == parent {
+				break
+			}
+			parent = up
+		}
+	}
+
+	if rel, err := filepath.Rel(cwd, parent); relative && err == nil {
+		return rel
+	}
+
+	return parent
+}
+
+// resolvePath makes the path absolute against the working directory read once, so that
+// the directories within a relative root are not resolved with a syscall each.
+func resolvePath(cwd, path string) string {
+	switch {
+	case filepath.IsAbs(path):
+		return filepath.Clean(path)
+
+	// paths relative to another drive or to the root of the current one on Windows
+	case cwd == "" || filepath.VolumeName(path) != "" || path != "" && os.IsPathSeparator(path[0]):
+		return absPath(path)
+	}
+
+	return filepath.Join(cwd, path)
+}
+
+// scanRootsConcurrently scans up to jobs roots at once. The first root is reported as it
+// is scanned, the reports of the others are held back and passed on root by root once
+// the previous roots are done, so the output is grouped by root as if they were scanned
+// one after another.
+func (v *visualiser) scanRootsConcurrently(ctx context.Context, roots []*entry, jobs int) {
+	recorders := make([]*recordingReporter, len(roots))
+	done := make([]chan struct{}, len(roots))
+	sem := make(chan struct{}, jobs)
+
+	for i, root := range roots {
+		done[i] = make(chan struct{})
+
+		var r reporter = v.reporter
+		if i > 0 {
+			recorders[i] = newRecordingReporter()
+			r = recorders[i]
+		}
+
+		sem <- struct{}{}
+		go func(root *entry, r reporter, done chan struct{}) {
+			defer close(done)
+			defer func() { <-sem }()
+
+			v.scanRoot(ctx, r, root)
+		}(root, r, done[i])
+	}
+
+	<-done[0]
+	for i := 1; i < len(roots); i++ {
+		<-done[i]
+		recorders[i].replayTo(v.reporter)
+	}
+}
+
+// recordedCall is a call made to the reporter, a report unless dirDone is set.
+type recordedCall struct {
+	e       *entry
+	dirDone bool
+}
+
+// recordingReporter keeps the calls made during a scan to pass them on later. Reporters
+// only keep track of the directories that something was reported in, so dirDone is only
+// recorded for them and the recording grows with the number of reported entries rather
+// than with the size of the tree.
+type recordingReporter struct {
+	calls   []recordedCall
+	pending map[*entry]bool
+}
+
+func newRecordingReporter() *recordingReporter {
+	return &recordingReporter{
+		pending: map[*entry]bool{},
+	}
+}
+
+func (r *recordingReporter) report(e *entry) {
+	r.calls = append(r.calls, recordedCall{e: e})
+	r.pending[e.parent] = true
+}
+
+func (r *recordingReporter) dirDone(dir *entry) {
+	if !r.pending[dir] {
+		return
+	}
+
+	delete(r.pending, dir)
+
+	r.calls = append(r.calls, recordedCall{e: dir, dirDone: true})
+	r.pending[dir.parent] = true
+}
+
+func (r *recordingReporter) finish(root *entry) error {
+	return nil
+}
+
+func (r *recordingReporter) replayTo(to reporter) {
+	for _, c := range r.calls {
+		if c.dirDone {
+			to.dirDone(c.e)
+		} else {
+			to.report(c.e)
+		}
+	}
+
+	r.calls = nil
+}
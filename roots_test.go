@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestCommonParent(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"/a/x", "/a/y"}, "/a"},
+		{[]string{"/a/x/1", "/a/y"}, "/a"},
+		{[]string{"/a/x", "/b/y"}, "/"},
+		{[]string{"tt/a", "tt/c/"}, "tt"},
+		{[]string{"a", "b"}, "."},
+		{[]string{"a", "/work/b"}, "/work"},
+		{[]string{"../a", "b"}, ".."},
+	}
+
+	for _, tt := range tests {
+		if got := commonParent("/work", tt.paths); got != tt.want {
+			t.Errorf("commonParent(%q) = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
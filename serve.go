@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const listenAddrDefault = "localhost:8080"
+
+// server scans the directory and serves the results as an interactive web page, the
+// directory can be scanned again from the page. The previous results are served until
+// the new scan is over.
+type server struct {
+	rootDirs []string
+	opts     visualiserOptions
+
+	// rescanning is held during a rescan, so that rescans do not run concurrently
+	rescanning sync.Mutex
+
+	mu        sync.Mutex
+	root      *entry
+	scannedAt time.Time
+}
+
+// serveMain implements the serve subcommand.
+func serveMain(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	scanFlags := addScanFlags(fs)
+	listenAddr := fs.String("listen", listenAddrDefault, "address to serve the web UI on")
+	fs.Parse(args)
+
+	s := &server{
+		rootDirs: scanFlags.dirs(),
+		opts:     scanFlags.options(nil),
+	}
+	s.opts.display.inodes = *scanFlags.inodes
+
+	root, err := s.scan()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	s.root, s.scannedAt = root, time.Now()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/rescan", s.handleRescan)
+
+	log.Printf("info: serving results on http://%v/", *listenAddr)
+
+	if err := http.ListenAndServe(*listenAddr, mux); err != nil {
+		log.Fatalf("could not serve: %v", err)
+	}
+}
+
+// scan scans the directories and returns the scanned tree.
+func (s *server) scan() (*entry, error) {
+	var root *entry
+
+	reporter := newTreeReporter(io.Discard, func(w io.Writer, r *entry) error {
+		root = r
+		return nil
+	})
+
+	visualiser, err := newVisualiser(s.opts, reporter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := visualiser.visualise(context.Background(), s.rootDirs...); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	root, scannedAt := s.root, s.scannedAt
+	s.mu.Unlock()
+
+	var treemap bytes.Buffer
+	if err := writeSVGTreemap(&treemap, root, s.opts.display); err != nil {
+		http.Error(w, fmt.Sprintf("could not render treemap: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	report := newHTMLReport(root, scannedAt, s.opts.display)
+	report.Treemap = template.HTML(treemap.String())
+	report.Rescan = true
+
+	if err := htmlTemplate.Execute(w, report); err != nil {
+		log.Printf("error: could not render page: %v", err)
+	}
+}
+
+func (s *server) handleRescan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// a page from another site must not be able to start a rescan
+	if isCrossOrigin(r) {
+		http.Error(w, "cross-origin request rejected", http.StatusForbidden)
+		return
+	}
+
+	s.rescanning.Lock()
+	root, err := s.scan()
+	s.rescanning.Unlock()
+
+	if err != nil {
+		log.Printf("error: %v", err)
+		http.Error(w, fmt.Sprintf("could not rescan: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	s.root, s.scannedAt = root, time.Now()
+	s.mu.Unlock()
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// isCrossOrigin tells whether the request was sent by a browser from a page of another
+// origin, requests not coming from browsers carry neither of the headers and pass.
+func isCrossOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "":
+	case "same-origin", "none":
+		return false
+	default:
+		return true
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+
+	return err != nil || u.Host != r.Host
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"log"
+	"os"
+)
+
+// isSkippedSnapshot tells whether the directory holds snapshots of a file system that are
+// not to be scanned: they reflect historical data and would dominate the results. ZFS
+// keeps them in .zfs/snapshot of a dataset, btrfs snapshots are subvolumes made from
+// other subvolumes. The info may be nil if the directory has not been stat-ed yet.
+func (v *visualiser) isSkippedSnapshot(dir *entry, info os.FileInfo) bool {
+	if !v.skipSnapshots || dir.parent == nil {
+		return false
+	}
+
+	if dir.name == "snapshot" && dir.parent.name == ".zfs" {
+		log.Printf("warning: skipping directory %v, it holds ZFS snapshots, use -include-snapshots to scan it", dir.path)
+		return true
+	}
+
+	if info != nil && isSnapshotSubvolume(dir.path, info) {
+		log.Printf("warning: skipping directory %v, it is a btrfs snapshot, use -include-snapshots to scan it", dir.path)
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	btrfsSuperMagic = 0x9123683e
+
+	// btrfsFirstFreeObjectID is the inode number of the root directory of a subvolume
+	btrfsFirstFreeObjectID = 256
+
+	btrfsIOCGetSubvolInfo = 0x81f8943c
+)
+
+type btrfsTimespec struct {
+	sec  uint64
+	nsec uint32
+}
+
+// btrfsSubvolInfo is struct btrfs_ioctl_get_subvol_info_args.
+type btrfsSubvolInfo struct {
+	treeID       uint64
+	name         [256]byte
+	parentID     uint64
+	dirID        uint64
+	generation   uint64
+	flags        uint64
+	uuid         [16]byte
+	parentUUID   [16]byte
+	receivedUUID [16]byte
+	ctransid     uint64
+	otransid     uint64
+	stransid     uint64
+	rtransid     uint64
+	ctime        btrfsTimespec
+	otime        btrfsTimespec
+	stime        btrfsTimespec
+	rtime        btrfsTimespec
+	_            [8]uint64
+}
+
+// isSnapshotSubvolume tells whether the directory is the root of a btrfs subvolume
+// snapshotted from another one. Only roots of subvolumes are looked into, they are told by
+// their inode numbers.
+func isSnapshotSubvolume(path string, info os.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st.Ino != btrfsFirstFreeObjectID {
+		return false
+	}
+
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil || uint32(fs.Type) != btrfsSuperMagic {
+		return false
+	}
+
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return false
+	}
+	defer syscall.Close(fd)
+
+	var subvol btrfsSubvolInfo
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL, uintptr(fd), btrfsIOCGetSubvolInfo, uintptr(unsafe.Pointer(&subvol)),
+	)
+	if errno != 0 {
+		return false
+	}
+
+	return subvol.parentUUID != [16]byte{}
+}
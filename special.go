@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync/atomic"
+)
+
+// specialCounts counts the entries that are neither regular files, directories nor
+// symbolic links, which are skipped unless asked to be counted.
+type specialCounts struct {
+	sockets atomic.Int64
+	pipes   atomic.Int64
+	devices atomic.Int64
+	other   atomic.Int64
+}
+
+func (c *specialCounts) add(typ fs.FileMode) {
+	switch {
+	case typ&fs.ModeSocket != 0:
+		c.sockets.Add(1)
+	case typ&fs.ModeNamedPipe != 0:
+		c.pipes.Add(1)
+	case typ&fs.ModeDevice != 0:
+		c.devices.Add(1)
+	default:
+		c.other.Add(1)
+	}
+}
+
+// String lists the counts that are not zero, it is empty if nothing was counted.
+func (c *specialCounts) String() string {
+	var parts []string
+
+	for _, n := range []struct {
+		count    int64
+		one, few string
+	}{
+		{c.sockets.Load(), "socket", "sockets"},
+		{c.pipes.Load(), "named pipe", "named pipes"},
+		{c.devices.Load(), "device", "devices"},
+		{c.other.Load(), "entry of unknown type", "entries of unknown types"},
+	} {
+		switch {
+		case n.count == 1:
+			parts = append(parts, "1 "+n.one)
+		case n.count > 1:
+			parts = append(parts, fmt.Sprintf("%v %v", n.count, n.few))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
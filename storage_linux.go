@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// isRotational reports whether the path resides on a spinning disk, ok is false if it
+// could not be found out.
+func isRotational(path string) (rotational, ok bool) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return false, false
+	}
+
+	dev := uint64(st.Dev)
+	major := (dev>>8)&0xfff | (dev>>32)&^0xfff
+	minor := dev&0xff | (dev>>12)&^0xff
+
+	// partitions do not have the queue directory, it belongs to the whole disk
+	sysDir := fmt.Sprintf("/sys/dev/block/%d:%d", major, minor)
+	for _, f := range []string{sysDir + "/queue/rotational", sysDir + "/../queue/rotational"} {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+
+		return strings.TrimSpace(string(data)) == "1", true
+	}
+
+	return false, false
+}
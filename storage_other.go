@@ -0,0 +1,7 @@
+//go:build !linux
+
+package main
+
+func isRotational(path string) (rotational, ok bool) {
+	return false, false
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package main
+
+func alternateStreamsSize(path string) int64 {
+	return 0
+}
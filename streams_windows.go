@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procFindFirstStreamW = kernel32.NewProc("FindFirstStreamW")
+	procFindNextStreamW  = kernel32.NewProc("FindNextStreamW")
+)
+
+// findStreamInfoStandard asks FindFirstStreamW for WIN32_FIND_STREAM_DATA
+const findStreamInfoStandard = 0
+
+// win32FindStreamData is WIN32_FIND_STREAM_DATA.
+type win32FindStreamData struct {
+	streamSize int64
+	streamName [syscall.MAX_PATH + 36]uint16
+}
+
+// alternateStreamsSize returns the total size of the alternate data streams of the file.
+// The Zone.Identifier streams Windows attaches to downloaded files are left out, they are
+// tiny and found everywhere.
+func alternateStreamsSize(path string) int64 {
+	p, err := syscall.UTF16PtrFromString(longPath(path))
+	if err != nil {
+		return 0
+	}
+
+	var data win32FindStreamData
+	h, _, _ := procFindFirstStreamW.Call(
+		uintptr(unsafe.Pointer(p)), findStreamInfoStandard, uintptr(unsafe.Pointer(&data)), 0,
+	)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		return 0
+	}
+	defer syscall.FindClose(syscall.Handle(h))
+
+	var size int64
+	for {
+		// the unnamed stream "::$DATA" is the contents of the file itself
+		name := syscall.UTF16ToString(data.streamName[:])
+		if !strings.HasPrefix(name, "::") && !strings.HasPrefix(name, ":Zone.Identifier:") {
+			size += data.streamSize
+		}
+
+		if ok, _, _ := procFindNextStreamW.Call(h, uintptr(unsafe.Pointer(&data))); ok == 0 {
+			return size
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+const (
+	summaryExt   = "ext"
+	summaryOwner = "owner"
+	summaryAge   = "age"
+)
+
+// summaryRows limits the rows of summaries that may have many of them, the rest are
+// summed up in a single row.
+const summaryRows = 20
+
+// summary sums up sizes of the scanned files by the groups they fall into, like their
+// extensions.
+type summary struct {
+	// title heads the column of the groups.
+	title string
+	// group tells the group the file falls into, label turns it into what is printed if
+	// it is not nil.
+	group func(e *entry, info os.FileInfo) string
+	label func(group string) string
+	// rows limits the number of rows printed, 0 means no limit.
+	rows int
+	// order lists all the groups in the order they are printed in, they are ranked by
+	// size if it is empty.
+	order []string
+
+	mu     sync.Mutex
+	groups map[string]*summaryGroup
+}
+
+type summaryGroup struct {
+	name  string
+	size  int64
+	files int64
+}
+
+// parseSummaries parses a comma-separated list of summaries.
+func parseSummaries(list string) ([]*summary, error) {
+	var summaries []*summary
+
+	for _, kind := range strings.Split(list, ",") {
+		s := &summary{groups: map[string]*summaryGroup{}}
+
+		switch strings.TrimSpace(kind) {
+		case "":
+			continue
+		case summaryExt:
+			s.title, s.group, s.rows = "Extension", extGroup, summaryRows
+		case summaryOwner:
+			s.title, s.group, s.label = "Owner", ownerGroup, ownerLabel
+		case summaryAge:
+			s.title, s.group, s.order = "Modified", ageGroup(time.Now()), ageBucketNames()
+		default:
+			return nil, fmt.Errorf(
+				"unknown summary '%v', expected one of: %v",
+				kind, strings.Join([]string{summaryExt, summaryOwner, summaryAge}, ", "),
+			)
+		}
+
+		summaries = append(summaries, s)
+	}
+
+	return summaries, nil
+}
+
+// extGroup groups files by their lower-cased extensions, the names of dot-files are not
+// taken for extensions.
+func extGroup(e *entry, info os.FileInfo) string {
+	ext := strings.ToLower(filepath.Ext(e.name))
+	if ext == "" || len(ext) == len(e.name) {
+		return "(no extension)"
+	}
+
+	return "*" + ext
+}
+
+// ownerGroup groups files by the IDs of their owners, names are looked up once the scan
+// is over.
+func ownerGroup(e *entry, info os.FileInfo) string {
+	uid, _, ok := fileOwner(info)
+	if !ok {
+		return ""
+	}
+
+	return strconv.FormatUint(uint64(uid), 10)
+}
+
+func ownerLabel(group string) string {
+	uid, err := strconv.ParseUint(group, 10, 32)
+	if err != nil {
+		return "(unknown)"
+	}
+
+	return userName(uint32(uid))
+}
+
+// ageBuckets group files by how long ago they were modified, files modified longer ago
+// than the last bucket are older.
+var ageBuckets = []struct {
+	name string
+	age  time.Duration
+}{
+	{"last week", 7 * ageUnits["d"]},
+	{"last month", 30 * ageUnits["d"]},
+	{"last 6 months", 182 * ageUnits["d"]},
+	{"last year", ageUnits["y"]},
+}
+
+const olderBucket = "older"
+
+func ageBucketNames() []string {
+	names := make([]string, 0, len(ageBuckets)+1)
+	for _, b := range ageBuckets {
+		names = append(names, b.name)
+	}
+
+	return append(names, olderBucket)
+}
+
+// ageGroup groups files by how long before now they were modified.
+func ageGroup(now time.Time) func(e *entry, info os.FileInfo) string {
+	return func(e *entry, info os.FileInfo) string {
+		age := now.Sub(e.mtime)
+		for _, b := range ageBuckets {
+			if age < b.age {
+				return b.name
+			}
+		}
+
+		return olderBucket
+	}
+}
+
+func (s *summary) add(e *entry, info os.FileInfo) {
+	name := s.group(e, info)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g := s.groups[name]
+	if g == nil {
+		g = &summaryGroup{name: name}
+		s.groups[name] = g
+	}
+
+	g.size += e.size
+	g.files++
+}
+
+// sorted returns the groups, the largest first unless their order is fixed. The groups
+// beyond the limit of rows are summed up in the last one.
+func (s *summary) sorted() []summaryGroup {
+	if len(s.order) > 0 {
+		groups := make([]summaryGroup, 0, len(s.order))
+		for _, name := range s.order {
+			g := summaryGroup{name: name}
+			if found := s.groups[name]; found != nil {
+				g = *found
+			}
+
+			groups = append(groups, g)
+		}
+
+		return groups
+	}
+
+	groups := make([]summaryGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		if s.label != nil {
+			g.name = s.label(g.name)
+		}
+
+		groups = append(groups, *g)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].size != groups[j].size {
+			return groups[i].size > groups[j].size
+		}
+
+		return groups[i].name < groups[j].name
+	})
+
+	if s.rows <= 0 || len(groups) <= s.rows {
+		return groups
+	}
+
+	rest := summaryGroup{name: fmt.Sprintf("(%v more)", len(groups)-s.rows+1)}
+	for _, g := range groups[s.rows-1:] {
+		rest.size += g.size
+		rest.files += g.files
+	}
+
+	return append(groups[:s.rows-1], rest)
+}
+
+// summarize adds the file to the summaries.
+func (v *visualiser) summarize(e *entry, info os.FileInfo) {
+	for _, s := range v.summaries {
+		s.add(e, info)
+	}
+}
+
+// writeSummaries prints the summaries as tables one after another.
+func (v *visualiser) writeSummaries(w io.Writer) error {
+	for i, s := range v.summaries {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "%v\tSize\tFiles\n", s.title)
+
+		for _, g := range s.sorted() {
+			fmt.Fprintf(tw, "%v\t%v\t%v\n", v.display.path(g.name), v.display.size(g.size), humanize.Comma(g.files))
+		}
+
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
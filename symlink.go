@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strconv"
+)
+
+// symlinkMode tells which symbolic links to directories and files are followed.
+type symlinkMode int
+
+const (
+	// symlinksNever does not follow any symbolic links, not even the roots.
+	symlinksNever symlinkMode = iota
+	// symlinksRoots follows the roots given on the command line only.
+	symlinksRoots
+	// symlinksAlways follows every symbolic link, loops are detected the way
+	// directories reached by several paths are.
+	symlinksAlways
+)
+
+// isLink tells whether the entry of the type stands for another path, be it a symbolic
+// link or, on Windows, a junction.
+func isLink(typ fs.FileMode) bool {
+	return typ&fs.ModeSymlink != 0 || irregularLinks && typ&fs.ModeIrregular != 0
+}
+
+// symlinkFlag sets the mode it was registered for, so that the last of -P, -H and -L
+// wins the way it does for du.
+type symlinkFlag struct {
+	mode *symlinkMode
+	set  symlinkMode
+}
+
+func (f symlinkFlag) IsBoolFlag() bool { return true }
+
+func (f symlinkFlag) String() string {
+	if f.mode == nil {
+		return "false"
+	}
+
+	return strconv.FormatBool(*f.mode == f.set)
+}
+
+func (f symlinkFlag) Set(s string) error {
+	on, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+
+	if on {
+		*f.mode = f.set
+	}
+
+	return nil
+}
+
+// isLoop tells whether a link found in the directory points to the directory itself or
+// to one of its ancestors, following it would never end.
+func isLoop(dir, target string) bool {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return false
+	}
+
+	return isWithin(abs, absTarget)
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !freebsd
+
+package main
+
+import "errors"
+
+var errNoTerminalSupport = errors.New("terminal control is not supported on this platform")
+
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errNoTerminalSupport
+}
+
+func terminalSize(fd uintptr) (int, int, error) {
+	return 0, 0, errNoTerminalSupport
+}
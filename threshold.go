@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// threshold is the size entries have to exceed to be reported, either a fixed one or a
+// percentage of the size of the root or the parent directory.
+type threshold struct {
+	size    int64
+	percent float64
+}
+
+// parseThreshold parses a size like 100MB or a percentage like 5%.
+func parseThreshold(s string) (threshold, error) {
+	if p, ok := strings.CutSuffix(s, "%"); ok {
+		percent, err := strconv.ParseFloat(p, 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return threshold{}, fmt.Errorf("expected a percentage between 0 and 100")
+		}
+
+		return threshold{percent: percent}, nil
+	}
+
+	size, err := humanize.ParseBigBytes(s)
+	if err != nil {
+		return threshold{}, err
+	}
+
+	return threshold{size: size.Int64()}, nil
+}
+
+// thresholdOf returns the size the entry has to exceed to be reported. Percentages are
+// only known once the whole tree is scanned, the reports wait for that.
+func (v *visualiser) thresholdOf(e *entry) int64 {
+	t := v.fileThreshold
+	if e.typ == entryDir {
+		t = v.dirThreshold
+	}
+
+	if t.percent == 0 {
+		return t.size
+	}
+
+	base := e.parent
+	if !v.percentOfParent || base == nil {
+		for base = e; base.parent != nil; base = base.parent {
+		}
+	}
+
+	return int64(float64(base.size) * t.percent / 100)
+}
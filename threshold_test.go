@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    threshold
+		wantErr bool
+	}{
+		{s: "100MB", want: threshold{size: 100_000_000}},
+		{s: "1GiB", want: threshold{size: 1 << 30}},
+		{s: "512", want: threshold{size: 512}},
+		{s: "0", want: threshold{}},
+		{s: "5%", want: threshold{percent: 5}},
+		{s: "0.5%", want: threshold{percent: 0.5}},
+		{s: "100%", want: threshold{percent: 100}},
+		{s: "101%", wantErr: true},
+		{s: "-1%", wantErr: true},
+		{s: "%", wantErr: true},
+		{s: "lots", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseThreshold(tt.s)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseThreshold(%q) = %+v, want an error", tt.s, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseThreshold(%q) failed: %v", tt.s, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseThreshold(%q) = %+v, want %+v", tt.s, got, tt.want)
+		}
+	}
+}
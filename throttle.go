@@ -0,0 +1,37 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// throttle spaces out directory reads so that no more than the given number of them
+// happen per second whatever the number of jobs is.
+type throttle struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newThrottle(perSec int) *throttle {
+	return &throttle{interval: time.Second / time.Duration(perSec)}
+}
+
+// wait blocks until the next read is allowed, a nil throttle never blocks.
+func (t *throttle) wait() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	delay := t.next.Sub(now)
+	t.next = t.next.Add(t.interval)
+	t.mu.Unlock()
+
+	time.Sleep(delay)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+)
+
+// topEntries keeps the n largest of the entries offered to it.
+type topEntries struct {
+	n int
+
+	mu      sync.Mutex
+	entries entryHeap
+}
+
+func newTopEntries(n int) *topEntries {
+	return &topEntries{n: n}
+}
+
+// offer keeps the entry if it is among the n largest so far, it tells whether it did.
+func (t *topEntries) offer(e *entry) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.entries) < t.n {
+		heap.Push(&t.entries, e)
+		return true
+	}
+
+	if e.size <= t.entries[0].size {
+		return false
+	}
+
+	t.entries[0] = e
+	heap.Fix(&t.entries, 0)
+
+	return true
+}
+
+// sorted returns the entries kept, the largest first.
+func (t *topEntries) sorted() []*entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries := append([]*entry(nil), t.entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].size > entries[j].size })
+
+	return entries
+}
+
+// entryHeap is a min-heap of entries by size, so that the smallest of the largest ones
+// is the one to drop.
+type entryHeap []*entry
+
+func (h entryHeap) Len() int           { return len(h) }
+func (h entryHeap) Less(i, j int) bool { return h[i].size < h[j].size }
+func (h entryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *entryHeap) Push(x any)        { *h = append(*h, x.(*entry)) }
+
+func (h *entryHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return e
+}
+
+// offerTop offers the entry to the lists of the largest files or directories, the
+// directories deeper than maxDepth and the roots are not among them.
+func (v *visualiser) offerTop(e *entry, base string) {
+	var top *topEntries
+	switch {
+	case e.typ == entryFile:
+		top = v.topFiles
+	case v.maxDepth <= 0 || e.depth <= v.maxDepth:
+		top = v.topDirs
+	}
+	if top == nil {
+		return
+	}
+
+	if !v.passesFilters(e) {
+		return
+	}
+
+	if top.offer(e) && e.path == "" {
+		e.path = childPath(base, e.name)
+	}
+}
+
+// reportTop reports the largest files and then the largest directories in place of the
+// entries exceeding the thresholds.
+func (v *visualiser) reportTop() {
+	for _, top := range []*topEntries{v.topFiles, v.topDirs} {
+		if top == nil {
+			continue
+		}
+
+		for _, e := range top.sorted() {
+			e.flagged = true
+			v.reporter.report(e)
+		}
+	}
+}
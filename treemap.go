@@ -0,0 +1,77 @@
+package main
+
+import "math"
+
+type treemapRect struct {
+	x, y, w, h float64
+}
+
+// squarify lays out the given areas (sorted in descending order) inside r using the
+// squarified treemap algorithm, which keeps aspect ratios of rectangles close to 1.
+func squarify(areas []float64, r treemapRect) []treemapRect {
+	rects := make([]treemapRect, 0, len(areas))
+
+	for i := 0; i < len(areas); {
+		side := math.Min(r.w, r.h)
+
+		j := i + 1
+		for j < len(areas) && worstAspectRatio(areas[i:j+1], side) <= worstAspectRatio(areas[i:j], side) {
+			j++
+		}
+
+		row := areas[i:j]
+
+		rowArea := 0.0
+		for _, a := range row {
+			rowArea += a
+		}
+
+		if r.w >= r.h {
+			rowWidth := rowArea / r.h
+			y := r.y
+
+			for _, a := range row {
+				h := a / rowWidth
+				rects = append(rects, treemapRect{r.x, y, rowWidth, h})
+				y += h
+			}
+
+			r.x += rowWidth
+			r.w -= rowWidth
+		} else {
+			rowHeight := rowArea / r.w
+			x := r.x
+
+			for _, a := range row {
+				w := a / rowHeight
+				rects = append(rects, treemapRect{x, r.y, w, rowHeight})
+				x += w
+			}
+
+			r.y += rowHeight
+			r.h -= rowHeight
+		}
+
+		i = j
+	}
+
+	return rects
+}
+
+func worstAspectRatio(row []float64, side float64) float64 {
+	sum, minArea, maxArea := 0.0, math.Inf(1), 0.0
+
+	for _, a := range row {
+		sum += a
+		minArea = math.Min(minArea, a)
+		maxArea = math.Max(maxArea, a)
+	}
+
+	if sum == 0 || minArea == 0 || side == 0 {
+		return math.Inf(1)
+	}
+
+	sum2, side2 := sum*sum, side*side
+
+	return math.Max(side2*maxArea/sum2, sum2/(side2*minArea))
+}
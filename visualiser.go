@@ -0,0 +1,1540 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// visualiserOptions configure the scan, they are usually filled from the command line.
+type visualiserOptions struct {
+	sizeThreshold string
+	ignoreRegexps []string
+
+	// fileThreshold and dirThreshold override sizeThreshold for files and directories,
+	// empty means sizeThreshold.
+	fileThreshold string
+	dirThreshold  string
+
+	// percentOf is what percentage thresholds are taken of: "root" or "parent", empty
+	// means the root.
+	percentOf string
+
+	// maxSize is the size of the largest entries reported, empty means no limit.
+	maxSize string
+
+	// reportTypes tells which entries are reported: f for files, d for directories,
+	// all or empty for both.
+	reportTypes string
+
+	// topFiles and topDirs are the numbers of the largest files and directories to
+	// report in place of the entries exceeding the thresholds, 0 for both means the
+	// thresholds are used.
+	topFiles int
+	topDirs  int
+
+	// onlyRegexps restrict the scan to the paths matching any of them and their
+	// contents, the directories leading to them are reported with what they hold.
+	onlyRegexps []string
+
+	// matchAgainst tells what ignoreRegexps and onlyRegexps are matched against, one of
+	// the match constants, empty means matchPath.
+	matchAgainst string
+
+	// ignoreCase matches the regexps and the exclude patterns whatever the case.
+	ignoreCase bool
+
+	// filters are glob patterns of files and directories to leave out or to bring back,
+	// excludeFrom are the files listing more patterns to leave out and presets the names
+	// of excludePresets adding theirs, those come before filters.
+	filters     []filterPattern
+	excludeFrom []string
+	presets     []string
+
+	// exts are comma-separated extensions of the only files to report, notExts of the
+	// files not to report, the files are counted anyway.
+	exts    string
+	notExts string
+
+	// olderThan and newerThan are the ages of the only files to report, as taken by
+	// parseAge, the files are counted anyway.
+	olderThan string
+	newerThan string
+
+	// notAccessedSince is the age of the last read of the only files to report.
+	notAccessedSince string
+
+	// perms are comma-separated permissions of the only files to report, as taken by
+	// parsePerms.
+	perms string
+
+	// owners and groups are comma-separated names or IDs of the users and groups owning
+	// the only files to count.
+	owners string
+	groups string
+
+	// maxDepth is the depth of the deepest entries reported, the roots being at depth 0,
+	// 0 means no limit.
+	maxDepth int
+
+	// jobs is the number of directories read concurrently, 0 picks it depending on
+	// the scanned storage.
+	jobs int
+
+	// cache enables reusing listings of directories unchanged since the previous run.
+	cache bool
+
+	// index is the file keeping listings of the scanned tree between runs, it
+	// overrides cache.
+	index string
+
+	// rawReadDir reads directories with raw system calls where supported.
+	rawReadDir bool
+
+	// gentle lowers the priority of the scan and reads one directory at a time
+	// unless jobs are set explicitly.
+	gentle bool
+
+	// maxDirsPerSec limits the rate of reading directories, 0 means no limit.
+	maxDirsPerSec int
+
+	// dirTimeout is how long reading a single directory may take before it is
+	// skipped, 0 means no limit.
+	dirTimeout time.Duration
+
+	// noDedup disables detecting directories reached by several paths, sparing a
+	// stat of every directory.
+	noDedup bool
+
+	// countLinks counts every hard link of a file instead of the first one found only.
+	countLinks bool
+
+	// shared finds out how much of the disk usage of files is shared with other files by
+	// reflinks, deduplication or snapshots.
+	shared bool
+
+	// countSpecial counts sockets, named pipes and devices as empty files instead of
+	// skipping them.
+	countSpecial bool
+
+	// diskUsage accounts files by the space they take on disk rather than by their
+	// apparent sizes.
+	diskUsage bool
+
+	// includeVirtual scans mount points of virtual file systems like proc.
+	includeVirtual bool
+
+	// includeSnapshots scans ZFS and btrfs snapshots.
+	includeSnapshots bool
+
+	// checkChanges stats directories again once they are scanned to flag the ones
+	// modified meanwhile.
+	checkChanges bool
+
+	// skipHidden ignores hidden files and directories.
+	skipHidden bool
+
+	// dirSizes adds the sizes of directories themselves to their totals.
+	dirSizes bool
+
+	// respectGitignore leaves out what .gitignore and .ignore files found during the
+	// scan describe.
+	respectGitignore bool
+
+	// noOwnIgnoreFiles scans what .spacevisignore files leave out.
+	noOwnIgnoreFiles bool
+
+	// fsTotals prints how much was found on each file system once the scan is over.
+	fsTotals bool
+
+	// summaries are comma-separated summaries of the scanned files to print once the
+	// scan is over, like ext for the totals of their extensions.
+	summaries string
+
+	// display renders paths in the tables printed once the scan is over.
+	display display
+	// tables is where the tables printed once the scan is over are written to, they are
+	// diagnostics written to stderr if it is nil.
+	tables io.Writer
+
+	// estimateSavings samples the reported files once the scan is over to estimate how
+	// much compressing them would save.
+	estimateSavings bool
+
+	// bundles reports macOS bundles like .app as single entries, nothing within them is
+	// reported.
+	bundles bool
+
+	// streams adds the alternate data streams of files on NTFS to their sizes, files
+	// keeping more than streamsThreshold in them are reported whatever their sizes.
+	streams          bool
+	streamsThreshold string
+
+	// oneFileSystem skips mount points of all other file systems.
+	oneFileSystem bool
+
+	// fsTypes are comma-separated types of the only file systems to scan, excludedFsTypes
+	// of the file systems not to scan.
+	fsTypes         string
+	excludedFsTypes string
+
+	// inodes sizes entries by the numbers of inodes they take instead of bytes, the size
+	// threshold is a number of inodes then.
+	inodes bool
+
+	// checkpoint is the file the state of the scan is periodically saved to, so that
+	// an interrupted scan can be continued with resume.
+	checkpoint         string
+	checkpointInterval time.Duration
+	resume             bool
+
+	// maxMemory is the size the heap should stay under, the scan gives up reading
+	// ahead, caching and keeping the tree for the database as it approaches it.
+	maxMemory string
+
+	// largeFirst scans probably large directories before the others.
+	largeFirst bool
+
+	// symlinks tells which symbolic links are followed.
+	symlinks symlinkMode
+
+	// db is the file the scanned tree is saved to, if any.
+	db string
+
+	// stream refuses everything keeping a memory of the whole tree.
+	stream bool
+
+	// progress may be nil if no progress should be shown.
+	progress scanProgress
+}
+
+type visualiser struct {
+	fileThreshold threshold
+	dirThreshold  threshold
+	maxSize       int64
+
+	// percentOfParent takes percentage thresholds of the parents instead of the roots,
+	// deferReports holds the reports back until the scan is over as the percentages
+	// are only known then
+	percentOfParent bool
+	deferReports    bool
+
+	topFiles *topEntries
+	topDirs  *topEntries
+
+	reportFiles   bool
+	reportDirs    bool
+	ignoreRegexps []*regexp.Regexp
+	onlyRegexps   []*regexp.Regexp
+	matchAgainst  string
+	rootDirs      []string
+	workDir       string
+	filters       []filterRule
+	hasIncludes   bool
+	exts          []string
+	notExts       []string
+	maxDepth      int
+
+	modifiedBefore time.Time
+	modifiedAfter  time.Time
+
+	owners map[uint32]bool
+	groups map[uint32]bool
+	perms  []fs.FileMode
+
+	accessedBefore time.Time
+	accessNotedMu  sync.Mutex
+	accessNoted    map[*fileSystem]bool
+
+	reporter reporter
+	keepTree bool
+	progress scanProgress
+
+	jobs       int
+	readers    chan struct{}
+	prefetched chan struct{}
+
+	cache      *dirCache
+	rawReadDir bool
+	throttle   *throttle
+	dirTimeout time.Duration
+
+	timings phaseTimings
+
+	needMtime bool
+	dedup     bool
+	db        string
+	scannedMu sync.Mutex
+	scanned   map[fileID]string
+
+	countLinks bool
+	diskUsage  bool
+	inodes     bool
+	linksMu    sync.Mutex
+	links      map[fileID]bool
+
+	countSpecial   bool
+	skippedSpecial specialCounts
+	shared         bool
+
+	streams          bool
+	streamsThreshold int64
+
+	// failures counts directories and files that could not be read, exceeded tells
+	// whether anything was reported
+	failures atomic.Int64
+	exceeded atomic.Bool
+
+	// denied are the directories that could not be read for lack of permissions, they
+	// are listed once the scan is over instead of being warned about one by one
+	deniedMu sync.Mutex
+	denied   []string
+
+	checkpoint         bool
+	checkpointInterval time.Duration
+
+	largeFirst bool
+	openDirs   atomic.Int64
+	symlinks   symlinkMode
+
+	mounts        map[string]*fileSystem
+	roots         []string
+	skipVirtual   bool
+	oneFileSystem bool
+	skipSnapshots bool
+
+	fsTypes         map[string]bool
+	excludedFsTypes map[string]bool
+	bundles         bool
+
+	checkChanges bool
+	changed      atomic.Int64
+
+	skipHidden  bool
+	dirSizes    bool
+	ignoreFiles []string
+
+	fsTotals  bool
+	volumesMu sync.Mutex
+	volumes   []volume
+
+	// cwd is the working directory read at the start of the scan, relative paths are
+	// resolved against it.
+	cwd string
+
+	summaries []*summary
+	flagged   *flaggedFiles
+	display   display
+	tables    io.Writer
+
+	maxMemory       int64
+	memoryLow       atomic.Bool
+	memorySaved     sync.Once
+	treeDropped     atomic.Bool
+	reportNeedsTree bool
+}
+
+func newVisualiser(opts visualiserOptions, r reporter) (*visualiser, error) {
+	v := &visualiser{
+		reporter:   r,
+		maxDepth:   opts.maxDepth,
+		progress:   opts.progress,
+		jobs:       opts.jobs,
+		rawReadDir: opts.rawReadDir,
+		dirTimeout: opts.dirTimeout,
+		dedup:      !opts.noDedup,
+		db:         opts.db,
+
+		checkpointInterval: opts.checkpointInterval,
+		largeFirst:         opts.largeFirst,
+		symlinks:           opts.symlinks,
+		scanned:            map[fileID]string{},
+		countLinks:         opts.countLinks,
+		countSpecial:       opts.countSpecial,
+		shared:             opts.shared,
+		streams:            opts.streams,
+		diskUsage:          opts.diskUsage,
+		inodes:             opts.inodes,
+		skipVirtual:        !opts.includeVirtual,
+		skipSnapshots:      !opts.includeSnapshots,
+		bundles:            opts.bundles,
+		checkChanges:       opts.checkChanges,
+		fsTotals:           opts.fsTotals,
+		skipHidden:         opts.skipHidden,
+		dirSizes:           opts.dirSizes,
+		oneFileSystem:      opts.oneFileSystem,
+		fsTypes:            parseFileSystemTypes(opts.fsTypes),
+		excludedFsTypes:    parseFileSystemTypes(opts.excludedFsTypes),
+		links:              map[fileID]bool{},
+		accessNoted:        map[*fileSystem]bool{},
+	}
+
+	if v.progress == nil {
+		v.progress = noProgress{}
+	}
+
+	if !opts.noOwnIgnoreFiles {
+		v.ignoreFiles = append(v.ignoreFiles, ownIgnoreFileName)
+	}
+	if opts.respectGitignore {
+		v.ignoreFiles = append(v.ignoreFiles, gitignoreFileNames...)
+	}
+
+	if opts.inodes && opts.diskUsage {
+		return nil, fmt.Errorf("entries cannot be sized both by inodes and by disk usage")
+	}
+	if opts.inodes && opts.shared {
+		return nil, fmt.Errorf("shared extents take bytes, they cannot be found out when counting inodes")
+	}
+	if opts.inodes && opts.streams {
+		return nil, fmt.Errorf("alternate data streams take bytes, they cannot be added when counting inodes")
+	}
+	if opts.inodes && opts.db != "" {
+		return nil, fmt.Errorf("the database keeps sizes in bytes, it cannot be saved when counting inodes")
+	}
+
+	if opts.maxDepth < 0 {
+		return nil, fmt.Errorf("invalid maximum depth '%v'", opts.maxDepth)
+	}
+
+	if opts.maxDirsPerSec < 0 {
+		return nil, fmt.Errorf("invalid rate of reading directories '%v'", opts.maxDirsPerSec)
+	}
+	if opts.maxDirsPerSec > 0 {
+		v.throttle = newThrottle(opts.maxDirsPerSec)
+	}
+
+	if opts.gentle {
+		if v.jobs == 0 {
+			v.jobs = 1
+		}
+
+		if err := lowerPriority(); err != nil {
+			log.Printf("warning: could not lower priority of the scan: %v", err)
+		}
+	}
+
+	sizeThresholdParsed, err := parseThreshold(opts.sizeThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("invalid size threshold '%v': %v", opts.sizeThreshold, err)
+	}
+
+	v.fileThreshold, v.dirThreshold = sizeThresholdParsed, sizeThresholdParsed
+
+	if opts.fileThreshold != "" {
+		if v.fileThreshold, err = parseThreshold(opts.fileThreshold); err != nil {
+			return nil, fmt.Errorf("invalid file size threshold '%v': %v", opts.fileThreshold, err)
+		}
+	}
+	if opts.dirThreshold != "" {
+		if v.dirThreshold, err = parseThreshold(opts.dirThreshold); err != nil {
+			return nil, fmt.Errorf("invalid directory size threshold '%v': %v", opts.dirThreshold, err)
+		}
+	}
+
+	switch opts.percentOf {
+	case "", "root":
+	case "parent":
+		v.percentOfParent = true
+	default:
+		return nil, fmt.Errorf("invalid base of percentage thresholds '%v'", opts.percentOf)
+	}
+	v.deferReports = v.fileThreshold.percent > 0 || v.dirThreshold.percent > 0
+
+	if opts.topFiles < 0 {
+		return nil, fmt.Errorf("invalid number of the largest files '%v'", opts.topFiles)
+	}
+	if opts.topFiles > 0 {
+		v.topFiles = newTopEntries(opts.topFiles)
+	}
+	if opts.topDirs < 0 {
+		return nil, fmt.Errorf("invalid number of the largest directories '%v'", opts.topDirs)
+	}
+	if opts.topDirs > 0 {
+		v.topDirs = newTopEntries(opts.topDirs)
+	}
+
+	switch opts.reportTypes {
+	case "", "all":
+		v.reportFiles, v.reportDirs = true, true
+	case "f", "file":
+		v.reportFiles = true
+	case "d", "dir":
+		v.reportDirs = true
+	default:
+		return nil, fmt.Errorf("invalid type of entries to report '%v'", opts.reportTypes)
+	}
+
+	if opts.maxSize != "" {
+		maxSizeParsed, err := humanize.ParseBigBytes(opts.maxSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maximum size '%v': %v", opts.maxSize, err)
+		}
+		if maxSizeParsed.Int64() <= min(v.fileThreshold.size, v.dirThreshold.size) {
+			return nil, fmt.Errorf("maximum size '%v' does not exceed the size thresholds", opts.maxSize)
+		}
+
+		v.maxSize = maxSizeParsed.Int64()
+	}
+
+	if opts.streams {
+		streamsThresholdParsed, err := humanize.ParseBigBytes(opts.streamsThreshold)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alternate data streams threshold '%v': %v", opts.streamsThreshold, err)
+		}
+
+		v.streamsThreshold = streamsThresholdParsed.Int64()
+	}
+
+	if opts.maxMemory != "" {
+		maxMemoryParsed, err := humanize.ParseBigBytes(opts.maxMemory)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory limit '%v': %v", opts.maxMemory, err)
+		}
+
+		v.maxMemory = maxMemoryParsed.Int64()
+	}
+
+	for _, ignoreRegexp := range opts.ignoreRegexps {
+		if ignoreRegexp == "" {
+			continue
+		}
+
+		ignoreRegexpParsed, err := compileRegexp(ignoreRegexp, opts.ignoreCase)
+		if err != nil {
+			return nil, fmt.Errorf("could not compile regexp '%s': %v", ignoreRegexp, err)
+		}
+		v.ignoreRegexps = append(v.ignoreRegexps, ignoreRegexpParsed)
+	}
+
+	switch opts.matchAgainst {
+	case "":
+		v.matchAgainst = matchPath
+	case matchPath, matchRelative, matchAbsolute, matchName:
+		v.matchAgainst = opts.matchAgainst
+	default:
+		return nil, fmt.Errorf("invalid kind of paths to match regexps against '%v'", opts.matchAgainst)
+	}
+
+	for _, onlyRegexp := range opts.onlyRegexps {
+		if onlyRegexp == "" {
+			continue
+		}
+
+		onlyRegexpParsed, err := compileRegexp(onlyRegexp, opts.ignoreCase)
+		if err != nil {
+			return nil, fmt.Errorf("could not compile regexp '%s': %v", onlyRegexp, err)
+		}
+		v.onlyRegexps = append(v.onlyRegexps, onlyRegexpParsed)
+	}
+
+	var filters []filterPattern
+	for _, name := range opts.presets {
+		patterns, err := presetPatterns(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, pattern := range patterns {
+			filters = append(filters, filterPattern{pattern: pattern})
+		}
+	}
+	for _, path := range opts.excludeFrom {
+		patterns, err := readPatterns(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read exclude patterns from %v: %v", path, err)
+		}
+		for _, pattern := range patterns {
+			filters = append(filters, filterPattern{pattern: pattern})
+		}
+	}
+	filters = append(filters, opts.filters...)
+
+	for _, f := range filters {
+		g, err := compileGlob(f.pattern, opts.ignoreCase)
+		if err != nil {
+			return nil, fmt.Errorf("could not compile filter pattern: %v", err)
+		}
+		v.filters = append(v.filters, filterRule{glob: g, include: f.include})
+		v.hasIncludes = v.hasIncludes || f.include
+	}
+
+	if v.exts, err = parseExts(opts.exts); err != nil {
+		return nil, fmt.Errorf("invalid -ext: %v", err)
+	}
+	if v.notExts, err = parseExts(opts.notExts); err != nil {
+		return nil, fmt.Errorf("invalid -not-ext: %v", err)
+	}
+
+	if opts.olderThan != "" {
+		age, err := parseAge(opts.olderThan)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -older-than: %v", err)
+		}
+
+		v.modifiedBefore = time.Now().Add(-age)
+	}
+	if opts.newerThan != "" {
+		age, err := parseAge(opts.newerThan)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -newer-than: %v", err)
+		}
+
+		v.modifiedAfter = time.Now().Add(-age)
+	}
+	if v.perms, err = parsePerms(opts.perms); err != nil {
+		return nil, fmt.Errorf("invalid -perm: %v", err)
+	}
+	v.display = opts.display
+	v.tables = opts.tables
+	if v.tables == nil {
+		v.tables = os.Stderr
+	}
+	if v.summaries, err = parseSummaries(opts.summaries); err != nil {
+		return nil, fmt.Errorf("invalid -summary: %v", err)
+	}
+
+	if v.owners, err = parseOwners(opts.owners, lookupUser); err != nil {
+		return nil, fmt.Errorf("invalid -owner: %v", err)
+	}
+	if v.groups, err = parseOwners(opts.groups, lookupGroup); err != nil {
+		return nil, fmt.Errorf("invalid -group: %v", err)
+	}
+
+	if opts.notAccessedSince != "" {
+		age, err := parseAge(opts.notAccessedSince)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -not-accessed-since: %v", err)
+		}
+
+		v.accessedBefore = time.Now().Add(-age)
+	}
+
+	loadStarted := time.Now()
+
+	switch {
+	case opts.checkpoint != "" && (opts.index != "" || opts.cache):
+		return nil, fmt.Errorf("checkpoints cannot be combined with a cache or an index")
+
+	case opts.checkpoint != "" && opts.resume:
+		if v.cache, err = loadDirCache(opts.checkpoint); err != nil {
+			return nil, err
+		}
+		v.checkpoint = true
+
+	case opts.checkpoint != "":
+		v.cache = newDirCache(opts.checkpoint)
+		v.checkpoint = true
+
+	case opts.resume:
+		return nil, fmt.Errorf("there is no checkpoint to resume from")
+
+	case opts.index != "":
+		if v.cache, err = loadDirCache(opts.index); err != nil {
+			return nil, err
+		}
+		v.cache.merge = true
+
+	case opts.cache:
+		path, err := defaultCacheFile()
+		if err != nil {
+			return nil, fmt.Errorf("could not locate cache: %v", err)
+		}
+
+		if v.cache, err = loadDirCache(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if v.cache != nil {
+		v.timings.measure("load cache", loadStarted)
+	}
+
+	if v.cache != nil && !v.accessedBefore.IsZero() {
+		return nil, fmt.Errorf("files are read without changing their directories, access times cannot be taken from the cache")
+	}
+
+	if t, ok := r.(treeNeeder); ok {
+		v.keepTree = t.needsTree()
+	}
+	if v.deferReports && opts.stream {
+		return nil, fmt.Errorf("percentage thresholds are only known once the whole tree is scanned, cannot stream")
+	}
+	if v.deferReports {
+		v.keepTree = true
+	}
+	if v.topFiles != nil || v.topDirs != nil {
+		// the largest entries are only known once the whole tree is scanned, they are
+		// kept aside rather than in the tree
+		v.deferReports = true
+	}
+	v.reportNeedsTree = v.keepTree
+
+	v.needMtime = true
+	if m, ok := r.(mtimeNeeder); ok {
+		v.needMtime = m.needsMtime()
+	}
+
+	if opts.symlinks == symlinksAlways && opts.noDedup {
+		return nil, fmt.Errorf("symbolic links cannot be followed without detecting directories reached by several paths, loops would not end")
+	}
+
+	if opts.stream && v.keepTree {
+		return nil, fmt.Errorf("the report needs the whole tree, cannot stream")
+	}
+	if opts.stream && opts.db != "" {
+		return nil, fmt.Errorf("the database is made of the whole tree, cannot stream")
+	}
+
+	if opts.db != "" {
+		v.keepTree = true
+	}
+	if opts.stream && v.cache != nil {
+		return nil, fmt.Errorf("the cache keeps every directory in memory, cannot stream")
+	}
+
+	if opts.estimateSavings && v.inodes {
+		return nil, fmt.Errorf("entries are sized by inodes, compressing them cannot be estimated")
+	}
+	if opts.estimateSavings {
+		v.flagged = &flaggedFiles{reporter: r}
+		v.reporter = v.flagged
+	}
+
+	return v, nil
+}
+
+// shouldReport tells whether the entry is within the size range, shallow enough and of a
+// type to be reported, and passes the filters.
+func (v *visualiser) shouldReport(e *entry) bool {
+	return (e.size > v.thresholdOf(e) || e.streams > v.streamsThreshold) &&
+		(v.maxSize <= 0 || e.size <= v.maxSize) && (v.maxDepth <= 0 || e.depth <= v.maxDepth) &&
+		(e.typ == entryFile && v.reportFiles || e.typ == entryDir && v.reportDirs) && v.passesFilters(e)
+}
+
+// passesFilters tells whether the entry is not left out of the report for lying within a
+// bundle or, for files, by their names, times or permissions.
+func (v *visualiser) passesFilters(e *entry) bool {
+	return !v.inBundle(e) && v.isReportedExt(e) && v.isReportedAge(e) && v.isReportedAccess(e) &&
+		v.isReportedPerm(e)
+}
+
+func (v *visualiser) shouldSkipDir(dir string) bool {
+	if len(v.ignoreRegexps) == 0 {
+		return false
+	}
+
+	dir = v.matchedPath(dir)
+	for _, re := range v.ignoreRegexps {
+		if re.MatchString(dir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesOnly tells whether the path is to be counted given the -only regexps.
+func (v *visualiser) matchesOnly(path string) bool {
+	if len(v.onlyRegexps) == 0 {
+		return true
+	}
+
+	path = v.matchedPath(path)
+	for _, re := range v.onlyRegexps {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compileRegexp compiles the regexp of -i or -only.
+func compileRegexp(expr string, ignoreCase bool) (*regexp.Regexp, error) {
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+
+	return regexp.Compile(expr)
+}
+
+// What the regexps of -i and -only are matched against.
+const (
+	// matchPath is the path as joined to the root given on the command line
+	matchPath = "path"
+	// matchRelative is the path relative to the root, . for the root itself
+	matchRelative = "relative"
+	// matchAbsolute is the absolute path
+	matchAbsolute = "absolute"
+	// matchName is the name alone
+	matchName = "name"
+)
+
+// matchedPath turns the path of a scanned entry into what regexps are matched against.
+func (v *visualiser) matchedPath(path string) string {
+	switch v.matchAgainst {
+	case matchRelative:
+		return v.relativePath(path)
+	case matchAbsolute:
+		if filepath.IsAbs(path) || v.workDir == "" {
+			return path
+		}
+		return filepath.Join(v.workDir, path)
+	case matchName:
+		return filepath.Base(path)
+	}
+
+	return path
+}
+
+// relativePath returns the path relative to the root it was joined to, the closest one
+// if the roots are nested.
+func (v *visualiser) relativePath(path string) string {
+	rel, found := path, false
+
+	for _, root := range v.rootDirs {
+		var r string
+		var ok bool
+
+		switch {
+		case filepath.Clean(path) == root:
+			r, ok = ".", true
+		case root == ".":
+			r, ok = path, !filepath.IsAbs(path)
+		case strings.HasSuffix(root, string(filepath.Separator)):
+			r, ok = strings.CutPrefix(path, root)
+		default:
+			r, ok = strings.CutPrefix(path, root+string(filepath.Separator))
+		}
+
+		if ok && (!found || len(r) < len(rel)) {
+			rel, found = r, true
+		}
+	}
+
+	return rel
+}
+
+// startCheckpoints saves the state of the scan every checkpointInterval until the
+// returned function is called.
+func (v *visualiser) startCheckpoints() func() {
+	if !v.checkpoint || v.checkpointInterval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(v.checkpointInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := v.cache.save(); err != nil {
+					log.Printf("warning: could not save checkpoint: %v", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// alreadyScanned tells whether the directory was reached by another path before, e.g.
+// through a bind mount or as a part of another root, so that it is not counted twice.
+func (v *visualiser) alreadyScanned(dir *entry, info os.FileInfo) bool {
+	if !v.dedup {
+		return false
+	}
+
+	id, ok := fileIDOf(info)
+	if !ok {
+		return false
+	}
+
+	v.scannedMu.Lock()
+	defer v.scannedMu.Unlock()
+
+	if prev, ok := v.scanned[id]; ok {
+		if prev == dir.path {
+			log.Printf("warning: skipping directory %v, it was already scanned as a root given before", dir.path)
+		} else {
+			log.Printf("warning: skipping directory %v, it was already scanned as %v", dir.path, prev)
+		}
+
+		return true
+	}
+
+	v.scanned[id] = dir.path
+
+	return false
+}
+
+// changedDuringScan tells whether the directory was modified or removed since it was
+// read, the mtime of a directory changes whenever an entry is added to or removed from it.
+func (v *visualiser) changedDuringScan(dir *entry) bool {
+	if dir.mtime.IsZero() {
+		return false
+	}
+
+	stat := os.Lstat
+	if dir.parent == nil {
+		// a root may be a symbolic link followed with -H
+		stat = os.Stat
+	}
+
+	info, err := stat(dir.path)
+	if err != nil {
+		return errors.Is(err, fs.ErrNotExist)
+	}
+
+	return !info.ModTime().Equal(dir.mtime)
+}
+
+// linkCounted tells whether the file is a hard link to a file counted already, so that
+// files linked from several places, e.g. by backups rotated with hard links, are only
+// counted once.
+func (v *visualiser) linkCounted(info os.FileInfo) bool {
+	if v.countLinks {
+		return false
+	}
+
+	id, ok := linkedFileID(info)
+	if !ok {
+		return false
+	}
+
+	v.linksMu.Lock()
+	defer v.linksMu.Unlock()
+
+	if v.links[id] {
+		return true
+	}
+
+	v.links[id] = true
+
+	return false
+}
+
+// sparseMinSize keeps small files, which often take a block or two whatever their sizes
+// are, from being taken for sparse.
+const sparseMinSize = 1 << 20
+
+// fileSizes returns the size the file is accounted by, the other one and whether the
+// file is sparse. Files whose disk usage is unknown are taken to use their apparent
+// sizes.
+func (v *visualiser) fileSizes(info os.FileInfo) (size, other int64, sparse bool) {
+	apparent := info.Size()
+	allocated, ok := allocatedSize(info)
+	if !ok {
+		allocated = apparent
+	}
+
+	sparse = isSparse(apparent, allocated)
+
+	if v.diskUsage {
+		return allocated, apparent, sparse
+	}
+
+	return apparent, allocated, sparse
+}
+
+// sharedSize finds out how much of the disk usage of the file just sized is shared with
+// other files, files not taking any space on disk are not looked into.
+func (v *visualiser) sharedSize(path string, e *entry) int64 {
+	allocated := e.otherSize
+	if v.diskUsage {
+		allocated = e.size
+	}
+	if allocated == 0 {
+		return 0
+	}
+
+	shared, ok := sharedSize(path)
+	if !ok {
+		return 0
+	}
+
+	// extents are rounded up to blocks of the file system
+	return min(shared, allocated)
+}
+
+// isSparse tells whether a file takes far less space on disk than its apparent size, be
+// it a sparse file or a file compressed by the filesystem.
+func isSparse(apparent, allocated int64) bool {
+	return apparent >= sparseMinSize && allocated < apparent/2
+}
+
+// errInterrupted is returned once the partial results of a cancelled scan are reported.
+var errInterrupted = errors.New("scan was interrupted, results are incomplete")
+
+// visualise scans the directories and reports the results, several directories are
+// reported under a nameless root holding them. If ctx is cancelled the scan stops as
+// soon as possible and whatever was found so far is reported.
+func (v *visualiser) visualise(ctx context.Context, dirs ...string) error {
+	rootDirs := make([]string, len(dirs))
+	for i, dir := range dirs {
+		rootDirs[i] = rootPath(dir)
+	}
+	dirs = dropNestedRoots(rootDirs)
+
+	for _, dir := range dirs {
+		v.rootDirs = append(v.rootDirs, filepath.Clean(dir))
+	}
+	if v.matchAgainst == matchAbsolute {
+		v.workDir, _ = os.Getwd()
+	}
+
+	roots := make([]*entry, 0, len(dirs))
+	for _, dir := range dirs {
+		roots = append(roots, &entry{
+			path: dir,
+			name: filepath.Base(dir),
+			typ:  entryDir,
+		})
+	}
+
+	jobs := v.jobs
+	if jobs <= 0 {
+		jobs = defaultJobs(dirs[0])
+	}
+
+	if jobs > 1 {
+		// the scanning goroutine itself is one of the jobs
+		v.readers = make(chan struct{}, jobs-1)
+		v.prefetched = make(chan struct{}, (jobs-1)*prefetchPerJob)
+	}
+
+	v.mounts = loadMounts()
+	if len(v.mounts) == 0 && (v.fsTypes != nil || v.excludedFsTypes != nil) {
+		log.Printf("warning: could not list mounted file systems, their types are not filtered")
+	}
+	v.cwd, _ = os.Getwd()
+	for _, dir := range dirs {
+		v.roots = append(v.roots, resolvePath(v.cwd, dir))
+	}
+
+	if v.cache != nil {
+		v.cache.cwd = v.cwd
+		for _, dir := range dirs {
+			v.cache.roots = append(v.cache.roots, v.cache.key(dir))
+		}
+	}
+
+	scanStarted := time.Now()
+
+	stopCheckpoints := v.startCheckpoints()
+	stopWatchingMemory := v.watchMemory()
+
+	v.progress.start()
+	if jobs > 1 && len(roots) > 1 && !rootsOverlap(dirs) {
+		v.scanRootsConcurrently(ctx, roots, jobs)
+	} else {
+		for _, root := range roots {
+			v.scanRoot(ctx, v.reporter, root)
+		}
+	}
+	v.progress.stop()
+
+	if skipped := v.skippedSpecial.String(); skipped != "" {
+		log.Printf("info: skipped %v, use -count-special to count them", skipped)
+	}
+	v.logDenied()
+	if changed := v.changed.Load(); changed > 0 {
+		log.Printf("warning: %v directories changed during the scan, their sizes are possibly stale", changed)
+	}
+
+	stopCheckpoints()
+	stopWatchingMemory()
+
+	v.timings.measure("scan", scanStarted)
+
+	// an interrupted scan has not seen the whole tree, the cache is better kept as is
+	// unless it is a checkpoint the scan is going to be resumed from
+	switch {
+	case v.checkpoint && ctx.Err() != nil:
+		if err := v.cache.save(); err != nil {
+			log.Printf("warning: could not save checkpoint: %v", err)
+		}
+
+	case v.checkpoint:
+		if err := os.Remove(v.cache.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("warning: could not remove checkpoint: %v", err)
+		}
+
+	case v.cache != nil && ctx.Err() == nil:
+		saveStarted := time.Now()
+
+		if err := v.cache.save(); err != nil {
+			log.Printf("warning: could not save cache: %v", err)
+		}
+
+		v.timings.measure("save cache", saveStarted)
+	}
+
+	defer v.timings.measure("report", time.Now())
+
+	switch {
+	case v.topFiles != nil || v.topDirs != nil:
+		v.reportTop()
+	case v.deferReports:
+		for _, root := range roots {
+			v.replayRoot(root)
+		}
+	}
+
+	top := roots[0]
+	if len(roots) > 1 {
+		// the roots are gathered under the directory holding them all
+		parent := commonParent(v.cwd, dirs)
+		top = &entry{path: parent, name: filepath.Base(parent), typ: entryDir, children: roots}
+		for _, root := range roots {
+			root.parent = top
+			top.account(root)
+		}
+	}
+
+	if v.db != "" && !v.treeDropped.Load() && ctx.Err() == nil {
+		if err := saveDB(v.db, top); err != nil {
+			log.Printf("error: could not save database %v: %v", v.db, err)
+		}
+	}
+
+	if err := v.reporter.finish(top); err != nil {
+		return fmt.Errorf("could not visualise %v: %v", strings.Join(dirs, ", "), err)
+	}
+
+	if v.fsTotals {
+		if err := v.writeVolumeTotals(v.tables); err != nil {
+			log.Printf("warning: could not print totals of file systems: %v", err)
+		}
+	}
+
+	// the tables are separated by empty lines the way the summaries are
+	if len(v.summaries) > 0 {
+		if v.fsTotals {
+			fmt.Fprintln(v.tables)
+		}
+		if err := v.writeSummaries(v.tables); err != nil {
+			log.Printf("warning: could not print summaries: %v", err)
+		}
+	}
+
+	if v.flagged != nil && ctx.Err() == nil {
+		if v.fsTotals || len(v.summaries) > 0 {
+			fmt.Fprintln(v.tables)
+		}
+		if err := v.writeCompressionEstimates(v.tables); err != nil {
+			log.Printf("warning: could not print compression estimates: %v", err)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return errInterrupted
+	}
+
+	return nil
+}
+
+// logDenied lists the directories skipped for lack of permissions.
+func (v *visualiser) logDenied() {
+	if len(v.denied) == 0 {
+		return
+	}
+
+	sort.Strings(v.denied)
+
+	log.Printf("warning: skipped %v directories that could not be read due to permissions:", len(v.denied))
+	for _, path := range v.denied {
+		log.Printf("warning:   %v", path)
+	}
+}
+
+// replay feeds a previously scanned tree to the reporter as if it was being scanned
+// right now.
+func (v *visualiser) replay(root *entry) error {
+	v.replayRoot(root)
+
+	if err := v.reporter.finish(root); err != nil {
+		return fmt.Errorf("could not visualise directory %v: %v", root.path, err)
+	}
+
+	return nil
+}
+
+// replayRoot reports the entries of the tree exceeding the thresholds, the root last.
+func (v *visualiser) replayRoot(root *entry) {
+	v.replayDir(root)
+
+	if v.shouldReport(root) {
+		root.flagged = true
+		v.exceeded.Store(true)
+		v.reporter.report(root)
+	}
+}
+
+func (v *visualiser) replayDir(dir *entry) {
+	for _, e := range dir.children {
+		if e.typ == entryDir {
+			v.replayDir(e)
+		}
+
+		if v.shouldReport(e) {
+			e.flagged = true
+			v.exceeded.Store(true)
+			v.reporter.report(e)
+		}
+	}
+
+	v.reporter.dirDone(dir)
+}
+
+// scanRoot scans the root given by the user and reports it to r.
+func (v *visualiser) scanRoot(ctx context.Context, r reporter, root *entry) {
+	// a root is stat-ed before reading it only if it may be a symbolic link not to be
+	// followed or it has to be looked up in the cache, otherwise it is stat-ed when read
+	var info os.FileInfo
+	switch {
+	case v.symlinks == symlinksNever:
+		lstat, err := os.Lstat(root.path)
+		if err == nil && isLink(lstat.Mode()) {
+			log.Printf("warning: skipping %v, it is a symbolic link, use -H or -L to follow it", root.path)
+			return
+		}
+		if err == nil {
+			info = lstat
+		}
+
+	case v.cache != nil:
+		info, _ = os.Stat(root.path)
+	}
+
+	root.only = v.matchesOnly(root.path)
+	root.mount = v.mountPointAt(root.path)
+
+	rootFS := root.mount
+	if rootFS == nil {
+		_, rootFS = v.fileSystemOf(root.path)
+	}
+	if rootFS != nil && !v.isScannedFileSystemType(rootFS.name) {
+		log.Printf("warning: skipping %v, it is on %v which is of a type not to be scanned", root.path, rootFS)
+		return
+	}
+	if root.mount != nil {
+		v.noteAccessTimes(root.path, root.mount)
+	} else {
+		v.noteAccessTimes(v.fileSystemOf(root.path))
+	}
+	if v.fsTotals {
+		if root.mount != nil {
+			v.addVolume(root.path, root.mount, root)
+		} else if mountPoint, fs := v.fileSystemOf(root.path); fs != nil {
+			v.addVolume(mountPoint, fs, root)
+		}
+	}
+
+	v.scanDir(ctx, r, root, nil, info, nil)
+
+	if !v.deferReports && v.shouldReport(root) {
+		root.flagged = true
+		v.exceeded.Store(true)
+		r.report(root)
+	}
+}
+
+// scanDir calculates size for the given directory recursively, opening it relative to
+// the parent if it is open. Entries exceeding the size thresholds are passed to the reporter
+// as soon as their size is known. The listing may be nil if the directory was not
+// prefetched.
+func (v *visualiser) scanDir(
+	ctx context.Context, r reporter, dir *entry, parent *openDir, info os.FileInfo, l *dirListing,
+) {
+	if ctx.Err() != nil {
+		dir.incomplete = true
+		return
+	}
+
+	if v.isSkippedSnapshot(dir, info) {
+		if l != nil {
+			l = v.listing(ctx, dir.path, parent, info, l)
+			v.discard(l.entries)
+			v.closeDir(l)
+		}
+		dir.incomplete = true
+
+		return
+	}
+
+	l = v.listing(ctx, dir.path, parent, info, l)
+	defer v.closeDir(l)
+	if err := l.err; err != nil {
+		dir.incomplete = true
+		if ctx.Err() != nil {
+			return
+		}
+
+		v.failures.Add(1)
+
+		if errors.Is(err, fs.ErrPermission) {
+			v.deniedMu.Lock()
+			v.denied = append(v.denied, dir.path)
+			v.deniedMu.Unlock()
+
+			return
+		}
+
+		log.Printf("error: could not read contents of directory %v: %v", dir.path, err)
+		log.Printf("warning: will skip directory %v in calculations", dir.path)
+
+		return
+	}
+
+	if v.memoryLow.Load() {
+		v.saveMemory()
+	}
+
+	if l.info != nil {
+		if dir.mtime.IsZero() {
+			dir.mtime = l.info.ModTime()
+		}
+
+		if info == nil && v.isSkippedSnapshot(dir, l.info) || v.alreadyScanned(dir, l.info) {
+			v.discard(l.entries)
+			dir.incomplete = true
+
+			return
+		}
+
+		// a directory that once held many entries keeps the space they took
+		if v.dirSizes && !v.inodes && dir.only && !dir.excluded {
+			dir.size, dir.otherSize, _ = v.fileSizes(l.info)
+		}
+	}
+
+	v.progress.enterDir(dir)
+
+	if v.inodes && dir.only && !dir.excluded {
+		// the directory takes an inode of its own
+		dir.size = 1
+	}
+
+	if v.largeFirst {
+		v.sortBySizeHint(dir.path, l.entries)
+	}
+
+	// paths of files are only built for the files that are reported or kept, a root may
+	// be given unclean though, its children are joined to its clean path
+	base := dir.path
+	if dir.parent == nil {
+		base = filepath.Clean(base)
+	}
+
+	if len(v.ignoreFiles) > 0 {
+		var inherited *ignoreList
+		if dir.parent != nil {
+			inherited = dir.parent.ignores
+		}
+
+		dir.ignores = loadIgnores(base, l.entries, v.ignoreFiles, inherited)
+	}
+
+	// entries too deep to be reported or within bundles are only kept for the database
+	keepTree := v.keepTree && !v.treeDropped.Load() && (v.db != "" ||
+		(v.maxDepth <= 0 || dir.depth < v.maxDepth) && !(v.bundles && isBundle(dir)) && !v.inBundle(dir))
+
+	for _, dirEntry := range l.entries {
+		// an excluded directory is looked into if -include may bring some of it back
+		excluded := v.isExcluded(base, dirEntry.Name(), dir.excluded)
+		if v.skipHidden && isHidden(dirEntry) || excluded && !(v.hasIncludes && dirEntry.Type().IsDir()) ||
+			dir.ignores.ignores(base, dirEntry.Name(), dirEntry.Type().IsDir()) {
+			v.discard([]listedEntry{dirEntry})
+			dir.incomplete = true
+
+			continue
+		}
+
+		e := &entry{
+			name:     dirEntry.Name(),
+			depth:    dir.depth + 1,
+			parent:   dir,
+			only:     dir.only,
+			excluded: excluded,
+		}
+
+		// directories not matching -only are looked into for what matches within them
+		if !e.only {
+			e.path = childPath(base, e.name)
+			e.only = v.matchesOnly(e.path)
+		}
+
+		typ, info, infoErr, parent := dirEntry.Type(), dirEntry.info, dirEntry.infoErr, l.dir
+		if isLink(typ) && v.symlinks == symlinksAlways {
+			e.path = childPath(base, e.name)
+
+			info, infoErr = os.Stat(e.path)
+			if infoErr != nil {
+				v.failures.Add(1)
+				log.Printf("warning: could not follow symbolic link %v: %v", e.path, infoErr)
+				continue
+			}
+
+			if target, err := filepath.EvalSymlinks(e.path); err == nil {
+				if info.IsDir() && isLoop(base, target) {
+					log.Printf("warning: skipping %v, it links to %v which contains it", e.path, target)
+					continue
+				}
+
+				e.target = target
+			}
+
+			// reading relative to the directory would not follow the link
+			typ, parent = info.Mode().Type(), nil
+		}
+
+		if !typ.IsDir() && (!e.only || !v.isOwned(info)) {
+			dir.incomplete = true
+			continue
+		}
+
+		switch {
+		case typ.IsRegular():
+			if err := infoErr; err != nil {
+				e.path = childPath(base, e.name)
+				v.failures.Add(1)
+				log.Printf("error: could not get info for file %v: %v", e.path, err)
+				log.Printf("warning: file %v will not be included in calculations", e.path)
+				dir.incomplete = true
+
+				continue
+			}
+
+			if v.linkCounted(info) {
+				continue
+			}
+
+			e.typ = entryFile
+			if v.inodes {
+				e.size = 1
+			} else {
+				e.size, e.otherSize, e.sparse = v.fileSizes(info)
+			}
+			if v.shared {
+				e.shared = v.sharedSize(childPath(base, e.name), e)
+			}
+			if v.streams {
+				e.streams = alternateStreamsSize(childPath(base, e.name))
+				e.size += e.streams
+				e.otherSize += e.streams
+			}
+			e.mtime = info.ModTime()
+			if !v.accessedBefore.IsZero() {
+				e.atime, _ = accessTime(info)
+			}
+			if len(v.perms) > 0 {
+				e.mode = info.Mode()
+			}
+
+			v.progress.addFile(e)
+			v.summarize(e, info)
+
+		case typ.IsDir():
+			e.path = childPath(base, e.name)
+			if v.shouldSkipDir(e.path) {
+				log.Printf(
+					"warning: ignoring directory '%v' due to matched ignore-regexp", e.path,
+				)
+				dir.incomplete = true
+
+				continue
+			}
+
+			if e.mount = v.mountPointAt(e.path); e.mount != nil && v.skipMount(e.path, e.mount) {
+				v.discard([]listedEntry{dirEntry})
+				dir.incomplete = true
+
+				continue
+			}
+			if e.mount != nil && v.fsTotals {
+				v.addVolume(e.path, e.mount, e)
+			}
+			if e.mount != nil {
+				v.noteAccessTimes(e.path, e.mount)
+			}
+
+			if info != nil {
+				e.mtime = info.ModTime()
+			}
+
+			e.typ = entryDir
+			v.scanDir(ctx, r, e, parent, info, dirEntry.sub)
+			dir.incomplete = dir.incomplete || e.incomplete
+
+		case v.inodes:
+			// symbolic links and special files take inodes as well
+			e.typ = entryFile
+			e.size = 1
+
+		case isLink(typ):
+			continue
+
+		case v.countSpecial:
+			e.typ = entryFile
+
+		default:
+			v.skippedSpecial.add(typ)
+			dir.incomplete = true
+
+			continue
+		}
+
+		if e.path == "" && (v.shouldReport(e) || keepTree) {
+			e.path = childPath(base, e.name)
+		}
+
+		if !v.deferReports && v.shouldReport(e) {
+			e.flagged = true
+			v.exceeded.Store(true)
+			r.report(e)
+		}
+		v.offerTop(e, base)
+
+		if keepTree {
+			dir.children = append(dir.children, e)
+		}
+
+		dir.account(e)
+	}
+
+	if v.checkChanges && v.changedDuringScan(dir) {
+		dir.changed = true
+		v.changed.Add(1)
+	}
+
+	if v.cache != nil && !v.inodes {
+		v.cache.setSize(dir.path, dir.size)
+	}
+
+	r.dirDone(dir)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+)
+
+// volume is a directory the scan entered a file system at: a root or a mount point.
+type volume struct {
+	mountPoint string
+	fs         *fileSystem
+	dir        *entry
+}
+
+// volumeTotal is what was found on a single file system.
+type volumeTotal struct {
+	mountPoint string
+	fs         *fileSystem
+	size       int64
+	files      int64
+}
+
+// addVolume remembers the directory the scan enters the file system at, its sizes are
+// read once the scan is over.
+func (v *visualiser) addVolume(path string, fs *fileSystem, dir *entry) {
+	path = resolvePath(v.cwd, path)
+
+	v.volumesMu.Lock()
+	v.volumes = append(v.volumes, volume{mountPoint: path, fs: fs, dir: dir})
+	v.volumesMu.Unlock()
+}
+
+// fileSystemOf finds the file system holding the path by the longest mount point it lies
+// within.
+func (v *visualiser) fileSystemOf(path string) (string, *fileSystem) {
+	abs := resolvePath(v.cwd, path)
+
+	var mountPoint string
+	for mp := range v.mounts {
+		if len(mp) > len(mountPoint) && isWithin(abs, mp) {
+			mountPoint = mp
+		}
+	}
+
+	if mountPoint == "" {
+		return "", nil
+	}
+
+	return mountPoint, v.mounts[mountPoint]
+}
+
+// volumeTotals sums up the entries by the file systems they reside on, a directory the
+// scan entered a file system at holds everything within it but the file systems mounted
+// deeper.
+func (v *visualiser) volumeTotals() []volumeTotal {
+	index := make(map[*entry]int, len(v.volumes))
+	for i, vol := range v.volumes {
+		index[vol.dir] = i
+	}
+
+	sizes := make([]int64, len(v.volumes))
+	files := make([]int64, len(v.volumes))
+	for i, vol := range v.volumes {
+		sizes[i] += vol.dir.size
+		files[i] += vol.dir.files
+
+		for p := vol.dir.parent; p != nil; p = p.parent {
+			if j, ok := index[p]; ok {
+				sizes[j] -= vol.dir.size
+				files[j] -= vol.dir.files
+				break
+			}
+		}
+	}
+
+	// several roots may reside on the same file system
+	byMount := map[string]*volumeTotal{}
+	for i, vol := range v.volumes {
+		t := byMount[vol.mountPoint]
+		if t == nil {
+			t = &volumeTotal{mountPoint: vol.mountPoint, fs: vol.fs}
+			byMount[vol.mountPoint] = t
+		}
+
+		t.size += sizes[i]
+		t.files += files[i]
+	}
+
+	totals := make([]volumeTotal, 0, len(byMount))
+	for _, t := range byMount {
+		totals = append(totals, *t)
+	}
+
+	sort.Slice(totals, func(i, j int) bool { return totals[i].size > totals[j].size })
+
+	return totals
+}
+
+// writeVolumeTotals prints the totals of the file systems the way df does.
+func (v *visualiser) writeVolumeTotals(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Filesystem\tType\tScanned\tFiles\tMounted on")
+
+	for _, t := range v.volumeTotals() {
+		fmt.Fprintf(
+			tw, "%v\t%v\t%v\t%v\t%v\n",
+			v.display.path(t.fs.device), t.fs.name, v.display.size(t.size), humanize.Comma(t.files), v.display.path(t.mountPoint),
+		)
+	}
+
+	return tw.Flush()
+}